@@ -6,6 +6,7 @@ import (
 	"ai/handlers"
 	"ai/types"
 	"ai/utils/logger"
+	"ai/utils/music"
 	"fmt"
 	"os"
 	"os/signal"
@@ -32,6 +33,11 @@ func init() {
 	session.Identify.Intents |= discordgo.IntentsAllWithoutPrivileged
 	session.AddHandler(ready)
 	session.AddHandler(handlers.InteractionCreateHandler)
+	session.AddHandler(handlers.VoiceStateUpdateHandler)
+
+	music.LoadAudioCache()
+	music.LoadLocalLibrary()
+	music.LoadSearchCache()
 }
 
 func main() {
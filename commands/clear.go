@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Clear(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	removed := voice.ClearQueue()
+	if removed == 0 {
+		respondWithError(s, i, "The queue is already empty.")
+		return
+	}
+
+	respond(s, i, fmt.Sprintf("🧹 Cleared **%d** track(s) from the queue.", removed))
+}
@@ -3,6 +3,10 @@ package commands
 import "github.com/bwmarrin/discordgo"
 
 var (
+	minQueuePage = 1.0
+	minVolume    = 0.0
+	minCrossfade = 0.0
+
 	Commands = []*discordgo.ApplicationCommand{
 		{
 			Name:        "play",
@@ -17,9 +21,133 @@ var (
 				},
 			},
 		},
+		{
+			Name:        "playfile",
+			Description: "Play an uploaded audio file (mp3, ogg, flac, wav or m4a)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "Audio file to play",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name: PlayAttachmentCommand,
+			Type: discordgo.MessageApplicationCommand,
+		},
 		{
 			Name:        "disconnect",
 			Description: "Disconnect the bot from the voice channel",
 		},
+		{
+			Name:        "247",
+			Description: "Toggle 24/7 mode so the bot stays in the voice channel",
+		},
+		{
+			Name:        "queue",
+			Description: "Show the tracks waiting to be played",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "page",
+					Description: "Page of the queue to show",
+					Required:    false,
+					MinValue:    &minQueuePage,
+				},
+			},
+		},
+		{
+			Name:        "nowplaying",
+			Description: "Show the track that is currently playing",
+		},
+		{
+			Name:        "skip",
+			Description: "Skip the current track",
+		},
+		{
+			Name:        "clear",
+			Description: "Remove all upcoming tracks from the queue",
+		},
+		{
+			Name:        "pause",
+			Description: "Pause the current track",
+		},
+		{
+			Name:        "resume",
+			Description: "Resume the paused track",
+		},
+		{
+			Name:        "seek",
+			Description: "Jump to a position in the current track",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "Timestamp like 1:23 or 83, or an offset like +30s or -15s",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "volume",
+			Description: "Show or change the playback volume",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "level",
+					Description: "Volume in percent (0-200)",
+					Required:    false,
+					MinValue:    &minVolume,
+					MaxValue:    200,
+				},
+			},
+		},
+		{
+			Name:        "filter",
+			Description: "Toggle audio filter presets or set a custom equalizer",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "preset",
+					Description: "Preset to toggle, or off to clear all filters",
+					Required:    false,
+					Choices:     filterChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "equalizer",
+					Description: "Bands as frequency:gain pairs, e.g. 60:+6, 1000:-3 (or off)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "crossfade",
+			Description: "Show or change how long consecutive tracks overlap",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "seconds",
+					Description: "Crossfade length in seconds (0-12, 0 turns it off)",
+					Required:    false,
+					MinValue:    &minCrossfade,
+					MaxValue:    12,
+				},
+			},
+		},
+		{
+			Name:        "gapless",
+			Description: "Play tracks back to back and trim silence at their edges",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Whether gapless playback is on",
+					Required:    true,
+				},
+			},
+		},
 	}
 )
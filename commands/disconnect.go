@@ -18,14 +18,14 @@ func Disconnect(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	voice, exists := music.VoiceConnection[guildID]
+	voice, exists := music.GetVoice(guildID)
 	if !exists {
 		respondWithError(s, i, "I'm not in a voice channel.")
 		return
 	}
 
 	if !isSameVC {
-		channel, err := s.Channel(voice.ChannelID)
+		channel, err := s.Channel(voice.VoiceChannelID())
 		if err == nil {
 			respondWithError(s, i, fmt.Sprintf("You must be in the same voice channel as me (**%s**) to use this command.", channel.Name))
 		} else {
@@ -34,7 +34,7 @@ func Disconnect(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	channel, err := s.Channel(voice.ChannelID)
+	channel, err := s.Channel(voice.VoiceChannelID())
 	channelName := "voice channel"
 	if err == nil {
 		channelName = channel.Name
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"ai/utils/music"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Filter(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respond(s, i, "🎛️ "+describeFilters(voice.ActiveFilters()))
+		return
+	}
+
+	messages := []string{}
+
+	for _, option := range options {
+		switch option.Name {
+		case "preset":
+			name := option.StringValue()
+			if name == "off" {
+				voice.ClearFilters()
+				messages = append(messages, "Cleared all filters.")
+				continue
+			}
+
+			enabled, err := voice.ToggleFilter(name)
+			if err != nil {
+				respondWithError(s, i, fmt.Sprintf("Unknown filter **%s**.", name))
+				return
+			}
+			if enabled {
+				messages = append(messages, fmt.Sprintf("Enabled **%s**.", name))
+			} else {
+				messages = append(messages, fmt.Sprintf("Disabled **%s**.", name))
+			}
+
+		case "equalizer":
+			input := strings.TrimSpace(option.StringValue())
+			if input == "off" {
+				voice.SetEqualizer(nil)
+				messages = append(messages, "Equalizer turned off.")
+				continue
+			}
+
+			bands, err := music.ParseEqualizer(input)
+			if err != nil {
+				respondWithError(s, i, fmt.Sprintf("Invalid equalizer: %v. Use bands like `60:+6, 1000:-3`.", err))
+				return
+			}
+			voice.SetEqualizer(bands)
+			messages = append(messages, "Equalizer updated.")
+		}
+	}
+
+	respond(s, i, fmt.Sprintf("🎛️ %s\n%s", strings.Join(messages, " "), describeFilters(voice.ActiveFilters())))
+}
+
+func describeFilters(active []string) string {
+	if len(active) == 0 {
+		return "No filters active."
+	}
+	return "Active filters: **" + strings.Join(active, ", ") + "**"
+}
+
+func filterChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, name := range music.FilterPresetNames() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: name,
+		})
+	}
+	return append(choices, &discordgo.ApplicationCommandOptionChoice{
+		Name:  "off",
+		Value: "off",
+	})
+}
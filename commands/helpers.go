@@ -1,6 +1,11 @@
 package commands
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"ai/utils/music"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
 
 func respondWithError(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -17,3 +22,41 @@ func updateResponse(s *discordgo.Session, i *discordgo.InteractionCreate, messag
 		Content: &message,
 	})
 }
+
+// requireSameVC responds with an error and returns false unless the bot is
+// connected in this guild and the user shares its voice channel.
+func requireSameVC(s *discordgo.Session, i *discordgo.InteractionCreate) (*music.VoiceInstance, bool) {
+	isSameVC, userChannelID := music.IsUserInSameVC(s, i.GuildID, i.Member.User.ID)
+
+	if userChannelID == "" {
+		respondWithError(s, i, "You must be in a voice channel to use this command.")
+		return nil, false
+	}
+
+	voice, exists := music.GetVoice(i.GuildID)
+	if !exists {
+		respondWithError(s, i, "I'm not in a voice channel.")
+		return nil, false
+	}
+
+	if !isSameVC {
+		channel, err := s.Channel(voice.VoiceChannelID())
+		if err == nil {
+			respondWithError(s, i, fmt.Sprintf("You must be in the same voice channel as me (**%s**) to use this command.", channel.Name))
+		} else {
+			respondWithError(s, i, "You must be in the same voice channel as me to use this command.")
+		}
+		return nil, false
+	}
+
+	return voice, true
+}
+
+func respond(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+		},
+	})
+}
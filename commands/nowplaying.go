@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"ai/utils/music"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func NowPlaying(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, exists := music.GetVoice(i.GuildID)
+	if !exists {
+		respondWithError(s, i, "I'm not in a voice channel.")
+		return
+	}
+
+	embed := voice.NowPlayingEmbed()
+	if embed == nil {
+		respondWithError(s, i, "Nothing is playing right now.")
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: voice.NowPlayingComponents(),
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	// Move live progress updates to the fresh message so they stay visible.
+	message, err := s.InteractionResponse(i.Interaction)
+	if err == nil {
+		voice.SetNowPlayingMessage(message.ChannelID, message.ID)
+	}
+}
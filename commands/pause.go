@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+func Pause(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	if voice.IsPaused() {
+		respondWithError(s, i, "Playback is already paused. Use /resume to continue.")
+		return
+	}
+
+	if !voice.Pause() {
+		respondWithError(s, i, "Nothing is playing right now.")
+		return
+	}
+
+	respond(s, i, "⏸️ Paused playback.")
+}
+
+func Resume(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	if !voice.Resume() {
+		respondWithError(s, i, "Playback is not paused.")
+		return
+	}
+
+	respond(s, i, "▶️ Resumed playback.")
+}
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"ai/config"
 	"ai/types"
 	"ai/utils/logger"
 	"ai/utils/music"
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -24,24 +27,8 @@ func Play(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	guildID := i.GuildID
-	userID := i.Member.User.ID
-
-	isSameVC, userChannelID := music.IsUserInSameVC(s, guildID, userID)
-
-	if userChannelID == "" {
-		respondWithError(s, i, "You must be in a voice channel to use this command.")
-		return
-	}
-
-	voice, exists := music.VoiceConnection[guildID]
-	if exists && !isSameVC {
-		channel, err := s.Channel(voice.ChannelID)
-		if err == nil {
-			respondWithError(s, i, fmt.Sprintf("I'm already in the voice channel **%s**. You must be in the same voice channel to control playback.", channel.Name))
-		} else {
-			respondWithError(s, i, "I'm already in a different voice channel. You must be in the same voice channel to control playback.")
-		}
+	userChannelID, ok := playVoiceChannel(s, i)
+	if !ok {
 		return
 	}
 
@@ -49,25 +36,41 @@ func Play(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	var trackURL, trackID, trackTitle string
-	var sourceType types.SourceType
+	var trackInfo types.MusicSearchResult
+	var trackURL, trackID string
 
 	if strings.Contains(input, "|") {
 		parts := strings.Split(input, "|")
-		if len(parts) >= 3 {
-			sourceType = types.SourceType(parts[0])
+		if len(parts) >= 2 {
+			sourceType := types.SourceType(parts[0])
 			trackID = parts[1]
-			trackURL = parts[2]
+			// Autocomplete leaves the URL out when it would not fit.
+			if len(parts) >= 3 {
+				trackURL = parts[2]
+			}
 
-			trackInfo, err := music.GetTrackInfo(trackID, sourceType)
-			if err != nil {
-				trackTitle = "Selected track"
+			info, err := music.GetTrackInfo(trackID, sourceType)
+			if message, ok := broadcastMessage(err); ok {
+				updateResponse(s, i, message)
+				return
+			}
+			if err != nil && !isSourceURL(sourceType, trackURL) {
+				updateResponse(s, i, "❌ Invalid track selection. Please try again.")
+				return
+			} else if err != nil {
+				trackInfo = types.MusicSearchResult{
+					Title:      "Selected track",
+					URL:        trackURL,
+					ID:         trackID,
+					SourceType: sourceType,
+				}
 			} else {
-				trackTitle = trackInfo.Title
+				trackInfo = info
+				trackURL = info.URL
 			}
 
 			if sourceType == types.Spotify {
-				ytTrack, err := music.GetYouTubeForSpotify(trackInfo.Title, trackInfo.Artist)
+				ytTrack, err := music.GetYouTubeForSpotify(trackInfo)
 				if err != nil {
 					updateResponse(s, i, "❌ Error fetching YouTube equivalent for Spotify track.")
 					return
@@ -81,45 +84,102 @@ func Play(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 	} else {
 		if music.IsYouTubeURL(input) {
-			trackInfo, err := music.GetYouTubeInfo(input)
+			videoID, listID := music.ParseYouTubeURL(input)
+			if listID != "" && videoID == "" {
+				playYouTubePlaylist(s, i, listID, "", userChannelID)
+				return
+			}
+			if listID != "" {
+				offerPlaylistChoice(s, i, videoID, listID)
+				return
+			}
+
+			info, err := music.GetYouTubeInfo(input)
+			if message, ok := broadcastMessage(err); ok {
+				updateResponse(s, i, message)
+				return
+			}
 			if err != nil {
 				updateResponse(s, i, "❌ Failed to get information for this YouTube URL.")
 				return
 			}
+			trackInfo = info
 			trackURL = input
-			trackID = trackInfo.ID
-			trackTitle = trackInfo.Title
-			sourceType = types.YouTube
+			trackID = info.ID
+		} else if music.IsTwitchURL(input) {
+			info, err := music.GetTwitchInfo(input)
+			if message, ok := broadcastMessage(err); ok {
+				updateResponse(s, i, message)
+				return
+			}
+			if err != nil {
+				updateResponse(s, i, "❌ Failed to get information for this Twitch URL.")
+				return
+			}
+			trackInfo = info
+			trackURL = info.URL
+			trackID = info.ID
+		} else if music.IsSoundCloudURL(input) {
+			info, playlist, err := music.GetSoundCloudURL(input)
+			if err != nil {
+				updateResponse(s, i, "❌ Failed to get information for this SoundCloud URL.")
+				return
+			}
+
+			if playlist != nil {
+				enqueueCollection(s, i, userChannelID, playlist.Name, playlist.FetchTracks)
+				return
+			}
+
+			trackInfo = info
+			trackURL = info.URL
+			trackID = info.ID
+		} else if music.IsSpotifyCollectionURL(input) {
+			playSpotifyCollection(s, i, input, userChannelID)
+			return
 		} else if music.IsSpotifyURL(input) {
-			trackInfo, err := music.GetSpotifyInfo(input)
+			info, err := music.GetSpotifyInfo(input)
 			if err != nil {
 				updateResponse(s, i, "❌ Failed to get information for this Spotify URL.")
 				return
 			}
 
-			ytTrack, err := music.GetYouTubeForSpotify(trackInfo.Title, trackInfo.Artist)
+			ytTrack, err := music.GetYouTubeForSpotify(info)
 			if err != nil {
 				updateResponse(s, i, "❌ Error fetching YouTube equivalent for Spotify track.")
 				return
 			}
+			trackInfo = info
 			trackURL = ytTrack.URL
 			trackID = ytTrack.ID
-			trackTitle = trackInfo.Title
-			sourceType = types.Spotify
+		} else if music.IsHTTPURL(input) {
+			info, err := music.GetHTTPAudioInfo(input)
+			if err != nil {
+				logger.Log(fmt.Sprintf("Failed to probe audio URL: %v", err), types.LogOptions{
+					Prefix: "Play Command",
+					Level:  types.Warn,
+				})
+				updateResponse(s, i, "❌ Couldn't find any playable audio at this URL.")
+				return
+			}
+			trackInfo = info
+			trackURL = input
+			trackID = info.ID
 		} else {
-			results, err := music.Search(input, 1)
+			ctx, cancel := context.WithTimeout(context.Background(), music.SearchTimeout)
+			results, err := music.Search(ctx, input, 1)
+			cancel()
 			if err != nil || len(results) == 0 {
 				updateResponse(s, i, "❌ No results found for your search query.")
 				return
 			}
 
 			result := results[0]
-			trackTitle = result.Title
+			trackInfo = result
 			trackID = result.ID
-			sourceType = result.SourceType
 
 			if result.SourceType == types.Spotify {
-				ytTrack, err := music.GetYouTubeForSpotify(result.Title, result.Artist)
+				ytTrack, err := music.GetYouTubeForSpotify(result)
 				if err != nil {
 					updateResponse(s, i, "❌ Error fetching YouTube equivalent for Spotify track.")
 					return
@@ -132,26 +192,187 @@ func Play(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 	}
 
-	voice, err := music.JoinVoiceChannel(s, guildID, userChannelID)
+	enqueueTrack(s, i, userChannelID, trackInfo, trackURL, trackID)
+}
+
+// broadcastMessage explains why a livestream cannot be played, returning
+// false for errors that are not about the broadcast state.
+func broadcastMessage(err error) (string, bool) {
+	var broadcastErr *music.BroadcastError
+	if errors.As(err, &broadcastErr) {
+		return "❌ " + broadcastErr.Error(), true
+	}
+	return "", false
+}
+
+// playVoiceChannel responds with an error and returns false unless the user
+// is in a voice channel the bot can play in, which is theirs unless the bot
+// is already connected elsewhere in the guild.
+func playVoiceChannel(s *discordgo.Session, i *discordgo.InteractionCreate) (string, bool) {
+	isSameVC, userChannelID := music.IsUserInSameVC(s, i.GuildID, i.Member.User.ID)
+
+	if userChannelID == "" {
+		respondWithError(s, i, "You must be in a voice channel to use this command.")
+		return "", false
+	}
+
+	voice, exists := music.GetVoice(i.GuildID)
+	if exists && !isSameVC {
+		channel, err := s.Channel(voice.VoiceChannelID())
+		if err == nil {
+			respondWithError(s, i, fmt.Sprintf("I'm already in the voice channel **%s**. You must be in the same voice channel to control playback.", channel.Name))
+		} else {
+			respondWithError(s, i, "I'm already in a different voice channel. You must be in the same voice channel to control playback.")
+		}
+		return "", false
+	}
+
+	return userChannelID, true
+}
+
+// joinForPlayback joins the user's voice channel and remembers where the
+// command was run for now-playing updates, editing the deferred response
+// with an error on failure.
+func joinForPlayback(s *discordgo.Session, i *discordgo.InteractionCreate, userChannelID string) (*music.VoiceInstance, bool) {
+	voice, err := music.JoinVoiceChannel(s, i.GuildID, userChannelID)
 	if err != nil {
 		logger.Log(fmt.Sprintf("Failed to join voice channel: %v", err), types.LogOptions{
 			Prefix: "Play Command",
 			Level:  types.Error,
 		})
 		updateResponse(s, i, "❌ Failed to join your voice channel.")
+		return nil, false
+	}
+
+	voice.SetTextChannel(i.ChannelID)
+	return voice, true
+}
+
+func enqueueTrack(s *discordgo.Session, i *discordgo.InteractionCreate, userChannelID string, trackInfo types.MusicSearchResult, trackURL, trackID string) {
+	voice, ok := joinForPlayback(s, i, userChannelID)
+	if !ok {
+		return
+	}
+
+	position := voice.Enqueue(&types.Track{
+		MusicSearchResult: trackInfo,
+		PlaybackURL:       trackURL,
+		PlaybackID:        trackID,
+		RequestedBy:       i.Member.User.ID,
+	})
+
+	if position == 0 {
+		updateResponse(s, i, fmt.Sprintf("🎵 Starting **%s**", trackInfo.Title))
+	} else {
+		updateResponse(s, i, fmt.Sprintf("➕ Added to queue at position **%d**: **%s**", position, trackInfo.Title))
+	}
+}
+
+// playSpotifyCollection queues every track of a Spotify playlist, album or
+// artist. Tracks are resolved to YouTube lazily when they come up.
+func playSpotifyCollection(s *discordgo.Session, i *discordgo.InteractionCreate, input, userChannelID string) {
+	collection, err := music.GetSpotifyCollection(input)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Failed to load Spotify collection: %v", err), types.LogOptions{
+			Prefix: "Play Command",
+			Level:  types.Error,
+		})
+		updateResponse(s, i, "❌ Failed to get information for this Spotify URL.")
 		return
 	}
 
-	updateResponse(s, i, fmt.Sprintf("🎵 Now playing: **%s**", trackTitle))
+	enqueueCollection(s, i, userChannelID, collection.Name, collection.FetchTracks)
+}
 
-	go func() {
-		err := voice.PlayYouTube(trackURL, trackID)
-		if err != nil {
-			logger.Log(fmt.Sprintf("Failed to play track: %v", err), types.LogOptions{
-				Prefix: "Play Command",
-				Level:  types.Error,
-			})
-			updateResponse(s, i, fmt.Sprintf("❌ Error playing **%s**: %v", trackTitle, err))
+// playYouTubePlaylist queues the videos of a YouTube playlist or mix.
+func playYouTubePlaylist(s *discordgo.Session, i *discordgo.InteractionCreate, listID, videoID, userChannelID string) {
+	playlist, err := music.GetYouTubePlaylist(listID, videoID, config.Config.MaxPlaylistSize)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Failed to load YouTube playlist: %v", err), types.LogOptions{
+			Prefix: "Play Command",
+			Level:  types.Error,
+		})
+		updateResponse(s, i, "❌ Failed to get information for this YouTube playlist.")
+		return
+	}
+
+	enqueueCollection(s, i, userChannelID, playlist.Name, playlist.FetchTracks)
+}
+
+// enqueueCollection queues a playlist-like source page by page, so the first
+// page starts playing while the rest is still being fetched. The number of
+// tracks is capped by MAX_PLAYLIST_SIZE.
+func enqueueCollection(s *discordgo.Session, i *discordgo.InteractionCreate, userChannelID, name string, fetch func(int, func([]types.MusicSearchResult) bool) error) {
+	voice, ok := joinForPlayback(s, i, userChannelID)
+	if !ok {
+		return
+	}
+
+	userID := i.Member.User.ID
+	added := 0
+	first := true
+
+	err := fetch(config.Config.MaxPlaylistSize, func(results []types.MusicSearchResult) bool {
+		if !voice.Active() {
+			return false
 		}
-	}()
+
+		tracks := make([]*types.Track, len(results))
+		for index, result := range results {
+			tracks[index] = &types.Track{
+				MusicSearchResult: result,
+				RequestedBy:       userID,
+			}
+			// Spotify results are matched on YouTube when they come up,
+			// everything else can be played as it is.
+			if result.SourceType != types.Spotify {
+				tracks[index].PlaybackURL = result.URL
+				tracks[index].PlaybackID = result.ID
+			}
+		}
+
+		voice.EnqueueMany(tracks)
+		added += len(tracks)
+
+		if first {
+			first = false
+			updateResponse(s, i, fmt.Sprintf("⏳ Queued **%d** tracks from **%s**, loading the rest...", added, name))
+		}
+		return true
+	})
+
+	if err != nil {
+		logger.Log(fmt.Sprintf("Failed to fetch tracks for %s: %v", name, err), types.LogOptions{
+			Prefix: "Play Command",
+			Level:  types.Error,
+		})
+	}
+
+	if added == 0 {
+		updateResponse(s, i, fmt.Sprintf("❌ No playable tracks found in **%s**.", name))
+		return
+	}
+
+	message := fmt.Sprintf("➕ Added **%d** tracks from **%s** to the queue.", added, name)
+	if err != nil {
+		message += " Some tracks could not be loaded."
+	} else if limit := config.Config.MaxPlaylistSize; limit > 0 && added >= limit {
+		message += fmt.Sprintf(" Only the first %d tracks were queued.", limit)
+	}
+	updateResponse(s, i, message)
+}
+
+// isSourceURL reports whether a URL carried in an autocomplete value can be
+// played when its track lookup fails. The value is user-controlled, so only
+// sources resolved through yt-dlp qualify, and only with a URL of their own.
+func isSourceURL(sourceType types.SourceType, trackURL string) bool {
+	switch sourceType {
+	case types.YouTube:
+		return music.IsYouTubeURL(trackURL)
+	case types.SoundCloud:
+		return music.IsSoundCloudURL(trackURL)
+	case types.Spotify:
+		return music.IsSpotifyURL(trackURL)
+	}
+	return false
 }
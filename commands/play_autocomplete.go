@@ -40,7 +40,7 @@ func PlayAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	results, err := music.Search(query, 10)
+	results, err := music.CachedSearch(query, 10)
 	if err != nil {
 		logger.Log(fmt.Sprintf("Search error: %v", err), types.LogOptions{
 			Prefix: "Play Autocomplete",
@@ -69,18 +69,30 @@ func PlayAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 
 		var displayName string
-		if result.SourceType == types.YouTube {
+		switch result.SourceType {
+		case types.YouTube:
 			displayName = fmt.Sprintf("▶️ %s - %s", result.Title, result.Artist)
-		} else {
+		case types.SoundCloud:
+			displayName = fmt.Sprintf("☁️ %s - %s", result.Title, result.Artist)
+		case types.Local:
+			displayName = "📁 " + result.Title
+			if result.Artist != "" {
+				displayName += " - " + result.Artist
+			}
+		default:
 			displayName = fmt.Sprintf("🎵 %s - %s", result.Title, result.Artist)
 		}
 
+		if result.Live {
+			displayName = "🔴 " + displayName
+		}
+
 		if len(displayName) > 100 {
 			displayName = displayName[:97] + "..."
 		}
 
 		valueStr := fmt.Sprintf("%s|%s|%s", result.SourceType, result.ID, result.URL)
-		if len(valueStr) > 100 {
+		if len(valueStr) > 100 || result.URL == "" {
 			valueStr = fmt.Sprintf("%s|%s", result.SourceType, result.ID)
 		}
 
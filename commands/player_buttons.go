@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"ai/utils/music"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func PlayerButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	userID := i.Member.User.ID
+
+	switch i.MessageComponentData().CustomID {
+	case music.PlayerPauseButton:
+		if !voice.Pause() && !voice.Resume() {
+			respondWithError(s, i, "Nothing is playing right now.")
+			return
+		}
+		updatePlayerMessage(s, i, voice)
+
+	case music.PlayerLoopButton:
+		voice.CycleLoop()
+		updatePlayerMessage(s, i, voice)
+
+	case music.PlayerSkipButton:
+		skipped, ok := voice.Skip()
+		if !ok {
+			respondWithError(s, i, "Nothing is playing right now.")
+			return
+		}
+		respond(s, i, fmt.Sprintf("⏭️ <@%s> skipped **%s**.", userID, skipped.Title))
+
+	case music.PlayerStopButton:
+		voice.StopPlayback()
+		respond(s, i, fmt.Sprintf("⏹️ <@%s> stopped playback and cleared the queue.", userID))
+
+	case music.PlayerShuffleButton:
+		count := voice.Shuffle()
+		if count < 2 {
+			respondWithError(s, i, "There is nothing to shuffle.")
+			return
+		}
+		respond(s, i, fmt.Sprintf("🔀 <@%s> shuffled **%d** upcoming track(s).", userID, count))
+	}
+}
+
+func updatePlayerMessage(s *discordgo.Session, i *discordgo.InteractionCreate, voice *music.VoiceInstance) {
+	embed := voice.NowPlayingEmbed()
+	if embed == nil {
+		respondWithError(s, i, "Nothing is playing right now.")
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: voice.NowPlayingComponents(),
+		},
+	})
+}
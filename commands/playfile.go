@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"ai/utils/music"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const PlayAttachmentCommand = "Play attachment"
+
+var attachmentExtensions = []string{".mp3", ".ogg", ".flac", ".wav", ".m4a"}
+
+func PlayFile(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	attachmentID, _ := data.Options[0].Value.(string)
+
+	var attachment *discordgo.MessageAttachment
+	if data.Resolved != nil {
+		attachment = data.Resolved.Attachments[attachmentID]
+	}
+	if attachment == nil {
+		respondWithError(s, i, "Could not read the uploaded file. Please try again.")
+		return
+	}
+
+	if !isAudioAttachment(attachment) {
+		respondWithError(s, i, "Unsupported file type. Upload an mp3, ogg, flac, wav or m4a file.")
+		return
+	}
+
+	playAttachments(s, i, []*discordgo.MessageAttachment{attachment})
+}
+
+// PlayAttachment handles the message context menu, queueing every audio file
+// attached to the selected message.
+func PlayAttachment(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+
+	var message *discordgo.Message
+	if data.Resolved != nil {
+		message = data.Resolved.Messages[data.TargetID]
+	}
+	if message == nil {
+		respondWithError(s, i, "Could not read that message. Please try again.")
+		return
+	}
+
+	attachments := []*discordgo.MessageAttachment{}
+	for _, attachment := range message.Attachments {
+		if isAudioAttachment(attachment) {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	if len(attachments) == 0 {
+		respondWithError(s, i, "That message has no audio attachments (mp3, ogg, flac, wav or m4a).")
+		return
+	}
+
+	playAttachments(s, i, attachments)
+}
+
+func isAudioAttachment(attachment *discordgo.MessageAttachment) bool {
+	return slices.Contains(attachmentExtensions, strings.ToLower(path.Ext(attachment.Filename)))
+}
+
+// playAttachments checks the files against the size and length limits and
+// queues the ones that pass, explaining why any others were left out.
+func playAttachments(s *discordgo.Session, i *discordgo.InteractionCreate, attachments []*discordgo.MessageAttachment) {
+	userChannelID, ok := playVoiceChannel(s, i)
+	if !ok {
+		return
+	}
+
+	maxSize := config.Config.MaxAttachmentMB * 1024 * 1024
+	maxLength := time.Duration(config.Config.MaxAttachmentLength) * time.Second
+
+	problems := []string{}
+	accepted := []*discordgo.MessageAttachment{}
+	for _, attachment := range attachments {
+		if maxSize > 0 && attachment.Size > maxSize {
+			problems = append(problems, fmt.Sprintf("**%s** is larger than %d MB.", attachment.Filename, config.Config.MaxAttachmentMB))
+			continue
+		}
+		accepted = append(accepted, attachment)
+	}
+
+	if len(accepted) == 0 {
+		respondWithError(s, i, strings.Join(problems, "\n"))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	tracks := []*types.Track{}
+	for _, attachment := range accepted {
+		info, length, err := music.GetAttachmentInfo(attachment.URL, attachment.Filename)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Failed to probe attachment %s: %v", attachment.Filename, err), types.LogOptions{
+				Prefix: "Play Command",
+				Level:  types.Warn,
+			})
+			problems = append(problems, fmt.Sprintf("**%s** could not be read as audio.", attachment.Filename))
+			continue
+		}
+
+		if maxLength > 0 && length > maxLength {
+			problems = append(problems, fmt.Sprintf("**%s** is longer than %s.", attachment.Filename, music.FormatDuration(maxLength)))
+			continue
+		}
+
+		tracks = append(tracks, &types.Track{
+			MusicSearchResult: info,
+			PlaybackURL:       attachment.URL,
+			PlaybackID:        info.ID,
+			RequestedBy:       i.Member.User.ID,
+		})
+	}
+
+	if len(tracks) == 0 {
+		updateResponse(s, i, "❌ "+strings.Join(problems, "\n"))
+		return
+	}
+
+	voice, ok := joinForPlayback(s, i, userChannelID)
+	if !ok {
+		return
+	}
+
+	var message string
+	position := voice.EnqueueMany(tracks)
+	switch {
+	case len(tracks) > 1:
+		message = fmt.Sprintf("➕ Added **%d** files to the queue.", len(tracks))
+	case position == 0:
+		message = fmt.Sprintf("🎵 Starting **%s**", tracks[0].Title)
+	default:
+		message = fmt.Sprintf("➕ Added to queue at position **%d**: **%s**", position, tracks[0].Title)
+	}
+
+	if len(problems) > 0 {
+		message += "\n⚠️ " + strings.Join(problems, "\n⚠️ ")
+	}
+	updateResponse(s, i, message)
+}
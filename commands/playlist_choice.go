@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"ai/utils/music"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	PlaylistChoicePrefix = "playlist"
+	playlistChoiceVideo  = "video"
+	playlistChoiceAll    = "all"
+)
+
+// The IDs are checked before they go into a custom ID, which is split on
+// ":" and may be at most 100 characters long.
+var (
+	youtubeVideoIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	youtubeListIDRegex  = regexp.MustCompile(`^[A-Za-z0-9_-]{2,64}$`)
+)
+
+// offerPlaylistChoice asks whether a watch URL that is part of a playlist
+// should queue just the video or the whole list. The IDs are carried in the
+// button custom IDs so no state has to be kept between the two interactions.
+func offerPlaylistChoice(s *discordgo.Session, i *discordgo.InteractionCreate, videoID, listID string) {
+	if !youtubeVideoIDRegex.MatchString(videoID) || !youtubeListIDRegex.MatchString(listID) {
+		updateResponse(s, i, "❌ This YouTube URL has an invalid video or playlist ID.")
+		return
+	}
+
+	message := "🔗 This video is part of a playlist. What should I queue?"
+	if music.IsYouTubeMix(listID) {
+		message = "🔗 This video is part of a YouTube Mix. What should I queue?"
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Just this video",
+					Style:    discordgo.PrimaryButton,
+					CustomID: playlistChoiceID(playlistChoiceVideo, videoID, listID),
+					Emoji:    &discordgo.ComponentEmoji{Name: "🎵"},
+				},
+				discordgo.Button{
+					Label:    "Whole playlist",
+					Style:    discordgo.SecondaryButton,
+					CustomID: playlistChoiceID(playlistChoiceAll, videoID, listID),
+					Emoji:    &discordgo.ComponentEmoji{Name: "📜"},
+				},
+			},
+		},
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &message,
+		Components: &components,
+	})
+}
+
+func playlistChoiceID(choice, videoID, listID string) string {
+	return strings.Join([]string{PlaylistChoicePrefix, choice, videoID, listID}, ":")
+}
+
+func PlaylistChoiceButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 4 {
+		return
+	}
+	choice, videoID, listID := parts[1], parts[2], parts[3]
+
+	if i.Message.Interaction != nil && i.Message.Interaction.User != nil && i.Message.Interaction.User.ID != i.Member.User.ID {
+		respondWithError(s, i, "Only the person who ran this command can choose.")
+		return
+	}
+
+	userChannelID, ok := playVoiceChannel(s, i)
+	if !ok {
+		return
+	}
+
+	// Replace the buttons with a loading message straight away so the choice
+	// cannot be made twice.
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "⏳ Loading...",
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+
+	switch choice {
+	case playlistChoiceVideo:
+		info, err := music.GetYouTubeInfoByID(videoID)
+		if message, ok := broadcastMessage(err); ok {
+			updateResponse(s, i, message)
+			return
+		}
+		if err != nil {
+			updateResponse(s, i, "❌ Failed to get information for this YouTube URL.")
+			return
+		}
+		enqueueTrack(s, i, userChannelID, info, info.URL, videoID)
+
+	case playlistChoiceAll:
+		playYouTubePlaylist(s, i, listID, videoID, userChannelID)
+
+	default:
+		updateResponse(s, i, "❌ Invalid selection. Please try again.")
+	}
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"ai/types"
+	"ai/utils/music"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const queuePageSize = 10
+
+func Queue(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	page := 1
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "page" {
+			page = int(option.IntValue())
+		}
+	}
+
+	current, upcoming := voice.QueueSnapshot()
+	if current == nil && len(upcoming) == 0 {
+		respondWithError(s, i, "The queue is empty.")
+		return
+	}
+
+	totalPages := max(1, (len(upcoming)+queuePageSize-1)/queuePageSize)
+	page = min(max(page, 1), totalPages)
+
+	var builder strings.Builder
+
+	if current != nil {
+		elapsed := music.FormatDuration(voice.Position())
+		if current.Live {
+			elapsed = "🔴 LIVE " + elapsed
+		} else if duration := voice.Duration(); duration > 0 {
+			elapsed += " / " + music.FormatDuration(duration)
+		}
+		builder.WriteString(fmt.Sprintf("🎵 **Now playing:** %s `%s`\n", formatTrackLine(current.Title, current.Artist, ""), elapsed))
+		if filters := voice.ActiveFilters(); len(filters) > 0 {
+			builder.WriteString(fmt.Sprintf("🎛️ Filters: %s\n", strings.Join(filters, ", ")))
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(upcoming) == 0 {
+		builder.WriteString("Nothing else is queued.")
+	} else {
+		builder.WriteString("**Up next:**\n")
+
+		start := (page - 1) * queuePageSize
+		end := min(start+queuePageSize, len(upcoming))
+		for index, track := range upcoming[start:end] {
+			builder.WriteString(fmt.Sprintf("`%d.` %s\n", start+index+1, formatTrackLine(track.Title, track.Artist, displayDuration(track))))
+		}
+
+		builder.WriteString(fmt.Sprintf("\nPage %d/%d • %d track(s) queued", page, totalPages, len(upcoming)))
+	}
+
+	respond(s, i, builder.String())
+}
+
+func displayDuration(track *types.Track) string {
+	if track.Live {
+		return "LIVE"
+	}
+	return track.Duration
+}
+
+func formatTrackLine(title, artist, duration string) string {
+	line := fmt.Sprintf("**%s**", title)
+	if artist != "" {
+		line += " - " + artist
+	}
+	if duration != "" && duration != "00:00" {
+		line += fmt.Sprintf(" `%s`", duration)
+	}
+	return line
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"ai/utils/music"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Seek(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	input := i.ApplicationCommandData().Options[0].StringValue()
+
+	target, err := parseSeekTime(input, voice.Position())
+	if err != nil {
+		respondWithError(s, i, "Invalid time. Use a timestamp like `1:23`, seconds like `83`, or a relative offset like `+30s` or `-15s`.")
+		return
+	}
+
+	target, err = voice.Seek(target)
+	if errors.Is(err, music.ErrNotSeekable) {
+		respondWithError(s, i, "Live streams can't be seeked.")
+		return
+	}
+	if err != nil {
+		respondWithError(s, i, "Nothing is playing right now.")
+		return
+	}
+
+	if duration := voice.Duration(); duration > 0 {
+		respond(s, i, fmt.Sprintf("⏩ Seeked to **%s** / %s.", music.FormatDuration(target), music.FormatDuration(duration)))
+	} else {
+		respond(s, i, fmt.Sprintf("⏩ Seeked to **%s**.", music.FormatDuration(target)))
+	}
+}
+
+// parseSeekTime accepts absolute timestamps ("1:23", "1:02:03", "83") and
+// offsets relative to the current position ("+30s", "-15s", "+1:00").
+func parseSeekTime(input string, position time.Duration) (time.Duration, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, fmt.Errorf("empty time")
+	}
+
+	sign := 0
+	switch input[0] {
+	case '+':
+		sign = 1
+		input = input[1:]
+	case '-':
+		sign = -1
+		input = input[1:]
+	}
+
+	offset, err := parseTimestamp(input)
+	if err != nil {
+		return 0, err
+	}
+
+	if sign == 0 {
+		return offset, nil
+	}
+	return position + time.Duration(sign)*offset, nil
+}
+
+func parseTimestamp(input string) (time.Duration, error) {
+	if strings.Contains(input, ":") {
+		parts := strings.Split(input, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("too many components in %q", input)
+		}
+
+		var total time.Duration
+		for _, part := range parts {
+			value, err := strconv.Atoi(part)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("invalid timestamp %q", input)
+			}
+			total = total*60 + time.Duration(value)
+		}
+		return total * time.Second, nil
+	}
+
+	if seconds, err := strconv.Atoi(input); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	duration, err := time.ParseDuration(input)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", input)
+	}
+	return duration, nil
+}
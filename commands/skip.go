@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Skip(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	skipped, ok := voice.Skip()
+	if !ok {
+		respondWithError(s, i, "Nothing is playing right now.")
+		return
+	}
+
+	respond(s, i, fmt.Sprintf("⏭️ Skipped **%s**.", skipped.Title))
+}
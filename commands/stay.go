@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"ai/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Stay(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	if config.Config.AlwaysConnected {
+		respondWithError(s, i, "24/7 mode is always on for this bot.")
+		return
+	}
+
+	enabled := !voice.IsStayConnected()
+	voice.SetStayConnected(enabled)
+
+	if enabled {
+		respond(s, i, "🌙 24/7 mode enabled. I'll stay in the voice channel until disconnected.")
+		return
+	}
+
+	voice.CheckListeners(s)
+	respond(s, i, "☀️ 24/7 mode disabled. I'll leave when the channel is empty or nothing is playing.")
+}
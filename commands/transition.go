@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Crossfade(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		if length := voice.CrossfadeLength(); length == 0 {
+			respond(s, i, "🔀 Crossfade is off.")
+		} else {
+			respond(s, i, fmt.Sprintf("🔀 Crossfade is **%ds**.", int(length/time.Second)))
+		}
+		return
+	}
+
+	length := voice.SetCrossfade(time.Duration(options[0].IntValue()) * time.Second)
+	if length == 0 {
+		respond(s, i, "🔀 Crossfade turned off.")
+		return
+	}
+
+	respond(s, i, fmt.Sprintf("🔀 Tracks will now crossfade over **%ds**.", int(length/time.Second)))
+}
+
+func Gapless(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	enabled := i.ApplicationCommandData().Options[0].BoolValue()
+	voice.SetGapless(enabled)
+
+	if enabled {
+		respond(s, i, "⏯️ Gapless playback enabled. Silence at the start and end of tracks will be trimmed.")
+	} else {
+		respond(s, i, "⏯️ Gapless playback disabled.")
+	}
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Volume(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	voice, ok := requireSameVC(s, i)
+	if !ok {
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respond(s, i, fmt.Sprintf("🔊 Volume is **%d%%**.", voice.CurrentVolume()))
+		return
+	}
+
+	level := voice.SetVolume(int(options[0].IntValue()))
+
+	icon := "🔊"
+	if level == 0 {
+		icon = "🔇"
+	} else if level < 50 {
+		icon = "🔉"
+	}
+
+	respond(s, i, fmt.Sprintf("%s Volume set to **%d%%**.", icon, level))
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"testing"
 
 	"github.com/joho/godotenv"
 )
@@ -14,10 +15,11 @@ var Config *types.BotConfig
 
 func init() {
 	logPrefix := "Config"
+	// Tests run without a .env file, so missing settings do not stop them.
 	logOptions := types.LogOptions{
 		Prefix: logPrefix,
 		Level:  types.Error,
-		Fatal:  true,
+		Fatal:  !testing.Testing(),
 	}
 
 	if err := godotenv.Load(); err != nil {
@@ -33,6 +35,20 @@ func init() {
 		Activity:            types.ActivityType(getIntEnv("ACTIVITY")),
 		ActivityMessage:     getEnv("ACTIVITY_MESSAGE"),
 		ActivityURL:         getEnv("ACTIVITY_URL"),
+		PlaybackMode:        types.PlaybackMode(strings.ToLower(getEnv("PLAYBACK_MODE"))),
+		CacheDir:            getEnv("CACHE_DIR"),
+		CacheMaxMB:          getIntEnvOr("CACHE_MAX_MB", 1024),
+		CacheStreamed:       getBoolEnv("CACHE_STREAMED"),
+		IdleTimeout:         getIntEnvOr("IDLE_TIMEOUT", 300),
+		EmptyChannelTimeout: getIntEnvOr("EMPTY_CHANNEL_TIMEOUT", 60),
+		AlwaysConnected:     getBoolEnv("ALWAYS_CONNECTED"),
+		MaxPlaylistSize:     getIntEnvOr("MAX_PLAYLIST_SIZE", 500),
+		MaxAttachmentMB:     getIntEnvOr("MAX_ATTACHMENT_MB", 25),
+		MaxAttachmentLength: getIntEnvOr("MAX_ATTACHMENT_LENGTH", 3600),
+		LibraryDir:          getEnv("LIBRARY_DIR"),
+		LibraryScanInterval: getIntEnvOr("LIBRARY_SCAN_INTERVAL", 300),
+		SearchCacheMaxKB:    getIntEnvOr("SEARCH_CACHE_MAX_KB", 4096),
+		SearchCacheTTL:      getIntEnvOr("SEARCH_CACHE_TTL", 600),
 	}
 
 	if Config.GuildID == "" {
@@ -51,12 +67,12 @@ func init() {
 		logger.Log("Unable to read Spotify client secret. environment variable SPOTIFY_CLIENT_SECRET is required", logOptions)
 	}
 
+	logOptions.Level = types.Warn
+	logOptions.Fatal = false
 	if Config.YoutubeAPIKey == "" {
-		logger.Log("Unable to read YouTube API key. environment variable YOUTUBE_API_KEY is required", logOptions)
+		logger.Log("YouTube API key is empty or not set. YouTube search will use yt-dlp instead", logOptions)
 	}
 
-	logOptions.Level = types.Warn
-	logOptions.Fatal = false
 	if Config.Activity == 0 {
 		logger.Log("Activity message is empty or not set. Defaulting to PLAYING", logOptions)
 		Config.Activity = types.PLAYING
@@ -72,6 +88,17 @@ func init() {
 		Config.ActivityURL = ""
 	}
 
+	if Config.PlaybackMode != types.StreamPlayback && Config.PlaybackMode != types.DownloadPlayback {
+		if Config.PlaybackMode != "" {
+			logger.Log("Unknown playback mode "+string(Config.PlaybackMode)+". Defaulting to stream", logOptions)
+		}
+		Config.PlaybackMode = types.StreamPlayback
+	}
+
+	if Config.CacheDir == "" {
+		Config.CacheDir = "./temp/cache"
+	}
+
 	logOptions.Level = types.Success
 	logOptions.Fatal = false
 	logger.Log("Config loaded successfully", logOptions)
@@ -96,3 +123,25 @@ func getIntEnv(key string) int {
 	}
 	return i
 }
+
+// getIntEnvOr reads an integer variable, falling back when it is unset or
+// invalid. An explicit 0 is kept so features can be turned off.
+func getIntEnvOr(key string, fallback int) int {
+	value := getEnv(key)
+	if value == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return i
+}
+
+func getBoolEnv(key string) bool {
+	value, err := strconv.ParseBool(getEnv(key))
+	if err != nil {
+		return false
+	}
+	return value
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"ai/commands"
+	"ai/utils/music"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// ComponentHandlers are keyed by the part of the custom ID before the
+	// first colon, so one handler can serve a group of related buttons.
+	ComponentHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		music.PlayerButtonPrefix:      commands.PlayerButton,
+		commands.PlaylistChoicePrefix: commands.PlaylistChoiceButton,
+	}
+)
@@ -1,6 +1,10 @@
 package handlers
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
 
 func InteractionCreateHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	switch i.Type {
@@ -13,5 +17,11 @@ func InteractionCreateHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 		if handler, ok := AutocompleteHandlers[i.ApplicationCommandData().Name]; ok {
 			handler(s, i)
 		}
+
+	case discordgo.InteractionMessageComponent:
+		prefix, _, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+		if handler, ok := ComponentHandlers[prefix]; ok {
+			handler(s, i)
+		}
 	}
 }
@@ -8,7 +8,21 @@ import (
 
 var (
 	SlashCommandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-		"play":       commands.Play,
-		"disconnect": commands.Disconnect,
+		"play":                         commands.Play,
+		"playfile":                     commands.PlayFile,
+		commands.PlayAttachmentCommand: commands.PlayAttachment,
+		"disconnect":                   commands.Disconnect,
+		"247":                          commands.Stay,
+		"queue":                        commands.Queue,
+		"nowplaying":                   commands.NowPlaying,
+		"skip":                         commands.Skip,
+		"clear":                        commands.Clear,
+		"pause":                        commands.Pause,
+		"resume":                       commands.Resume,
+		"seek":                         commands.Seek,
+		"volume":                       commands.Volume,
+		"filter":                       commands.Filter,
+		"crossfade":                    commands.Crossfade,
+		"gapless":                      commands.Gapless,
 	}
 )
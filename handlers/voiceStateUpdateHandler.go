@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"ai/utils/music"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func VoiceStateUpdateHandler(s *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
+	if vs.UserID == s.State.User.ID {
+		music.HandleBotVoiceState(s, vs)
+		return
+	}
+
+	voice, exists := music.GetVoice(vs.GuildID)
+
+	if !exists {
+		return
+	}
+
+	voice.CheckListeners(s)
+}
@@ -9,6 +9,13 @@ const (
 	STREAMING
 )
 
+type PlaybackMode string
+
+const (
+	StreamPlayback   PlaybackMode = "stream"
+	DownloadPlayback PlaybackMode = "download"
+)
+
 type BotConfig struct {
 	GuildID             string
 	DiscordToken        string
@@ -18,4 +25,18 @@ type BotConfig struct {
 	Activity            ActivityType
 	ActivityMessage     string
 	ActivityURL         string
+	PlaybackMode        PlaybackMode
+	CacheDir            string
+	CacheMaxMB          int
+	CacheStreamed       bool
+	IdleTimeout         int
+	EmptyChannelTimeout int
+	AlwaysConnected     bool
+	MaxPlaylistSize     int
+	MaxAttachmentMB     int
+	MaxAttachmentLength int
+	LibraryDir          string
+	LibraryScanInterval int
+	SearchCacheMaxKB    int
+	SearchCacheTTL      int
 }
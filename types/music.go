@@ -3,8 +3,21 @@ package types
 type SourceType string
 
 const (
-	YouTube SourceType = "youtube"
-	Spotify SourceType = "spotify"
+	YouTube    SourceType = "youtube"
+	Spotify    SourceType = "spotify"
+	SoundCloud SourceType = "soundcloud"
+	HTTP       SourceType = "http"
+	Attachment SourceType = "attachment"
+	Local      SourceType = "local"
+	Twitch     SourceType = "twitch"
+)
+
+type LoopMode int
+
+const (
+	LoopOff LoopMode = iota
+	LoopTrack
+	LoopQueue
 )
 
 type MusicSearchResult struct {
@@ -15,26 +28,28 @@ type MusicSearchResult struct {
 	Duration   string
 	Thumbnail  string
 	SourceType SourceType
+	Live       bool
+}
+
+type Track struct {
+	MusicSearchResult
+	PlaybackURL string
+	PlaybackID  string
+	RequestedBy string
+}
+
+// PlaybackSource is the source the audio is actually played from. Spotify
+// tracks are played through their YouTube match.
+func (t *Track) PlaybackSource() SourceType {
+	if t.SourceType == Spotify {
+		return YouTube
+	}
+	return t.SourceType
 }
 
 type SpotifySearchResponse struct {
 	Tracks struct {
-		Items []struct {
-			ID      string `json:"id"`
-			Name    string `json:"name"`
-			Artists []struct {
-				Name string `json:"name"`
-			} `json:"artists"`
-			Album struct {
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"album"`
-			DurationMs   int `json:"duration_ms"`
-			ExternalUrls struct {
-				Spotify string `json:"spotify"`
-			} `json:"external_urls"`
-		} `json:"items"`
+		Items []SpotifyTrack `json:"items"`
 	} `json:"tracks"`
 }
 
@@ -44,9 +59,10 @@ type YouTubeSearchResponse struct {
 			VideoID string `json:"videoId"`
 		} `json:"id"`
 		Snippet struct {
-			Title        string `json:"title"`
-			ChannelTitle string `json:"channelTitle"`
-			Thumbnails   struct {
+			Title                string `json:"title"`
+			ChannelTitle         string `json:"channelTitle"`
+			LiveBroadcastContent string `json:"liveBroadcastContent"`
+			Thumbnails           struct {
 				Default struct {
 					URL string `json:"url"`
 				} `json:"default"`
@@ -57,3 +73,84 @@ type YouTubeSearchResponse struct {
 		} `json:"snippet"`
 	} `json:"items"`
 }
+
+type SpotifyTrack struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	IsLocal bool   `json:"is_local"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Images []struct {
+			URL string `json:"url"`
+		} `json:"images"`
+	} `json:"album"`
+	DurationMs   int `json:"duration_ms"`
+	ExternalUrls struct {
+		Spotify string `json:"spotify"`
+	} `json:"external_urls"`
+}
+
+type SpotifyPlaylistResponse struct {
+	Name string `json:"name"`
+}
+
+type SpotifyPlaylistTracksResponse struct {
+	Items []struct {
+		Track *SpotifyTrack `json:"track"`
+	} `json:"items"`
+	Next  string `json:"next"`
+	Total int    `json:"total"`
+}
+
+type SpotifyAlbumResponse struct {
+	Name   string `json:"name"`
+	Images []struct {
+		URL string `json:"url"`
+	} `json:"images"`
+}
+
+type SpotifyAlbumTracksResponse struct {
+	Items []SpotifyTrack `json:"items"`
+	Next  string         `json:"next"`
+	Total int            `json:"total"`
+}
+
+type SpotifyArtistResponse struct {
+	Name string `json:"name"`
+}
+
+type SpotifyArtistTopTracksResponse struct {
+	Tracks []SpotifyTrack `json:"tracks"`
+}
+
+type YouTubePlaylistResponse struct {
+	Items []struct {
+		Snippet struct {
+			Title string `json:"title"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+type YouTubePlaylistItemsResponse struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []struct {
+		Snippet struct {
+			Title                  string `json:"title"`
+			VideoOwnerChannelTitle string `json:"videoOwnerChannelTitle"`
+			Thumbnails             struct {
+				High struct {
+					URL string `json:"url"`
+				} `json:"high"`
+			} `json:"thumbnails"`
+			ResourceID struct {
+				VideoID string `json:"videoId"`
+			} `json:"resourceId"`
+		} `json:"snippet"`
+		Status struct {
+			PrivacyStatus string `json:"privacyStatus"`
+		} `json:"status"`
+	} `json:"items"`
+}
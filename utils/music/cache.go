@@ -0,0 +1,445 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheIndexFile = "index.json"
+	// maxCacheFills caps concurrent background downloads of streamed tracks.
+	maxCacheFills    = 2
+	cacheFillTimeout = 10 * time.Minute
+)
+
+var cacheFileName = regexp.MustCompile(`^[0-9a-f]{64}\.mp3$`)
+
+type cacheEntry struct {
+	Key      string    `json:"key"`
+	Hash     string    `json:"hash"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// audioCache stores tracks by content hash, keyed by source and ID, with LRU eviction.
+type audioCache struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	entries map[string]*cacheEntry
+	pins    map[string]int
+	filling map[string]bool
+	fills   chan struct{}
+}
+
+var AudioCache = &audioCache{
+	entries: make(map[string]*cacheEntry),
+	pins:    make(map[string]int),
+	filling: make(map[string]bool),
+	fills:   make(chan struct{}, maxCacheFills),
+}
+
+func cacheKey(source types.SourceType, id string) string {
+	return string(source) + ":" + id
+}
+
+// LoadAudioCache reads the index, keeping only entries whose files pass their checksum.
+func LoadAudioCache() {
+	c := AudioCache
+
+	dir := config.Config.CacheDir
+	maxSize := int64(config.Config.CacheMaxMB) * 1024 * 1024
+
+	if maxSize <= 0 {
+		c.mu.Lock()
+		c.dir, c.maxSize = dir, 0
+		c.mu.Unlock()
+
+		logger.Log("Audio cache disabled", types.LogOptions{
+			Prefix: "Audio Cache",
+			Level:  types.Info,
+		})
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Log("Failed to create cache directory: "+err.Error(), types.LogOptions{
+			Prefix: "Audio Cache",
+			Level:  types.Error,
+		})
+		return
+	}
+
+	var stored []*cacheEntry
+	if data, err := os.ReadFile(filepath.Join(dir, cacheIndexFile)); err == nil {
+		if err := json.Unmarshal(data, &stored); err != nil {
+			logger.Log("Cache index is corrupt, starting empty: "+err.Error(), types.LogOptions{
+				Prefix: "Audio Cache",
+				Level:  types.Warn,
+			})
+			stored = nil
+		}
+	}
+
+	entries := make(map[string]*cacheEntry)
+	verified := make(map[string]bool)
+	for _, entry := range stored {
+		valid, checked := verified[entry.Hash]
+		if !checked {
+			hash, size, err := hashFile(filepath.Join(dir, entry.Hash+".mp3"))
+			valid = err == nil && hash == entry.Hash && size == entry.Size
+			verified[entry.Hash] = valid
+		}
+		if valid {
+			entries[entry.Key] = entry
+		}
+	}
+
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || name == cacheIndexFile || verified[strings.TrimSuffix(name, ".mp3")] {
+			continue
+		}
+		if !isCacheFile(name) {
+			logger.Log("Leaving unknown file in the cache directory: "+name, types.LogOptions{
+				Prefix: "Audio Cache",
+				Level:  types.Debug,
+			})
+			continue
+		}
+		os.Remove(filepath.Join(dir, name))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dir = dir
+	c.maxSize = maxSize
+	c.entries = entries
+
+	c.evictLocked()
+	c.saveLocked()
+
+	logger.Log(fmt.Sprintf("Audio cache loaded: %d track(s), %d MB", len(c.entries), c.sizeLocked()/(1024*1024)), types.LogOptions{
+		Prefix: "Audio Cache",
+		Level:  types.Success,
+	})
+}
+
+// Acquire returns a cached file, pinned until the returned release is called.
+func (c *audioCache) Acquire(source types.SourceType, id string) (string, func(), bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[cacheKey(source, id)]
+	if !ok {
+		c.mu.Unlock()
+		return "", nil, false
+	}
+
+	hash, size := entry.Hash, entry.Size
+	path := c.filePath(hash)
+	c.pins[hash]++
+	entry.LastUsed = time.Now()
+	c.mu.Unlock()
+
+	release := c.releaser(hash)
+
+	if info, err := os.Stat(path); err != nil || info.Size() != size {
+		logger.Log("Cached file is missing or truncated, removing: "+entry.Key, types.LogOptions{
+			Prefix: "Audio Cache",
+			Level:  types.Warn,
+		})
+
+		c.mu.Lock()
+		if c.entries[entry.Key] == entry {
+			delete(c.entries, entry.Key)
+		}
+		c.mu.Unlock()
+
+		release()
+		return "", nil, false
+	}
+
+	c.mu.Lock()
+	c.saveLocked()
+	c.mu.Unlock()
+	return path, release, true
+}
+
+// releaser returns a function that unpins a file once.
+func (c *audioCache) releaser(hash string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			if c.pins[hash]--; c.pins[hash] <= 0 {
+				delete(c.pins, hash)
+			}
+			c.removeUnusedLocked(hash)
+			c.evictLocked()
+			c.saveLocked()
+		})
+	}
+}
+
+// Store moves a downloaded file into the cache and returns its pinned path.
+func (c *audioCache) Store(source types.SourceType, id, fileName string) (string, func(), error) {
+	if !c.enabled() {
+		return "", nil, fmt.Errorf("audio cache is disabled")
+	}
+
+	hash, size, err := hashFile(fileName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.filePath(hash)
+	if _, err := os.Stat(path); err != nil {
+		if err := moveFile(fileName, path); err != nil {
+			return "", nil, err
+		}
+	} else {
+		os.Remove(fileName)
+	}
+
+	key := cacheKey(source, id)
+	old, replaced := c.entries[key]
+
+	c.entries[key] = &cacheEntry{
+		Key:      key,
+		Hash:     hash,
+		Size:     size,
+		LastUsed: time.Now(),
+	}
+	c.pins[hash]++
+
+	if replaced && old.Hash != hash {
+		c.removeUnusedLocked(old.Hash)
+	}
+
+	c.evictLocked()
+	c.saveLocked()
+	return path, c.releaser(hash), nil
+}
+
+func (c *audioCache) enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.maxSize > 0
+}
+
+// Fill downloads a streamed track into the cache in the background.
+func (c *audioCache) Fill(source types.SourceType, trackURL, id string) {
+	key := cacheKey(source, id)
+
+	c.mu.Lock()
+	_, cached := c.entries[key]
+	if c.maxSize <= 0 || cached || c.filling[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.filling[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.filling, key)
+			c.mu.Unlock()
+		}()
+
+		c.fills <- struct{}{}
+		defer func() { <-c.fills }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), cacheFillTimeout)
+		defer cancel()
+
+		fileName, err := downloadAudio(ctx, trackURL, id)
+		if err != nil {
+			logger.Log("Failed to download track for the cache: "+err.Error(), types.LogOptions{
+				Prefix: "Audio Cache",
+				Level:  types.Warn,
+			})
+			return
+		}
+
+		_, release, err := c.Store(source, id, fileName)
+		if err != nil {
+			os.Remove(fileName)
+			return
+		}
+		release()
+
+		logger.Log("Cached streamed track: "+key, types.LogOptions{
+			Prefix: "Audio Cache",
+			Level:  types.Debug,
+		})
+	}()
+}
+
+func (c *audioCache) filePath(hash string) string {
+	return filepath.Join(c.dir, hash+".mp3")
+}
+
+func (c *audioCache) sizeLocked() int64 {
+	var size int64
+	seen := make(map[string]bool)
+	for _, entry := range c.entries {
+		if !seen[entry.Hash] {
+			seen[entry.Hash] = true
+			size += entry.Size
+		}
+	}
+	return size
+}
+
+func (c *audioCache) evictLocked() {
+	if c.maxSize <= 0 {
+		return
+	}
+
+	size := c.sizeLocked()
+	if size <= c.maxSize {
+		return
+	}
+
+	candidates := make([]*cacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		if c.pins[entry.Hash] == 0 {
+			candidates = append(candidates, entry)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+	})
+
+	for _, entry := range candidates {
+		if size <= c.maxSize {
+			break
+		}
+
+		logger.Log("Evicting cached track: "+entry.Key, types.LogOptions{
+			Prefix: "Audio Cache",
+			Level:  types.Debug,
+		})
+		c.removeLocked(entry)
+		size = c.sizeLocked()
+	}
+}
+
+func (c *audioCache) removeLocked(entry *cacheEntry) {
+	delete(c.entries, entry.Key)
+	c.removeUnusedLocked(entry.Hash)
+}
+
+// removeUnusedLocked deletes a file that is neither referenced nor pinned.
+func (c *audioCache) removeUnusedLocked(hash string) {
+	if c.pins[hash] > 0 {
+		return
+	}
+
+	for _, entry := range c.entries {
+		if entry.Hash == hash {
+			return
+		}
+	}
+	os.Remove(c.filePath(hash))
+}
+
+func (c *audioCache) saveLocked() {
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+
+	tmpPath := filepath.Join(c.dir, cacheIndexFile+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		logger.Log("Failed to write cache index: "+err.Error(), types.LogOptions{
+			Prefix: "Audio Cache",
+			Level:  types.Error,
+		})
+		return
+	}
+	os.Rename(tmpPath, filepath.Join(c.dir, cacheIndexFile))
+}
+
+// isCacheFile reports whether the cache itself could have created name.
+func isCacheFile(name string) bool {
+	return cacheFileName.MatchString(name) || strings.HasSuffix(name, ".mp3.part") || name == cacheIndexFile+".tmp"
+}
+
+func hashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// moveFile renames src to dst, copying when they are on different devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := dst + ".part"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Remove(src)
+}
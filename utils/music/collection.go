@@ -0,0 +1,22 @@
+package music
+
+import "ai/types"
+
+// limitPages wraps a page callback so that no more than limit tracks are
+// delivered in total. The returned function reports whether fetching should
+// continue. A limit of 0 or less disables the cap.
+func limitPages(limit int, onPage func([]types.MusicSearchResult) bool) func([]types.MusicSearchResult) bool {
+	delivered := 0
+
+	return func(tracks []types.MusicSearchResult) bool {
+		if limit > 0 && delivered+len(tracks) > limit {
+			tracks = tracks[:limit-delivered]
+		}
+		delivered += len(tracks)
+
+		if len(tracks) > 0 && !onPage(tracks) {
+			return false
+		}
+		return limit <= 0 || delivered < limit
+	}
+}
@@ -0,0 +1,125 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// decoder is a running ffmpeg process that turns an input into 48kHz stereo
+// PCM frames.
+type decoder struct {
+	cmd       *exec.Cmd
+	out       *bufio.Reader
+	stream    io.Closer
+	tempo     float64
+	closeOnce sync.Once
+}
+
+// startDecoder spawns ffmpeg for the input at the given offset, applying the
+// guild's current filter graph.
+func (v *VoiceInstance) startDecoder(input string, offset time.Duration) (*decoder, error) {
+	live := v.isLive()
+
+	// Radio stations are read here rather than by ffmpeg, so their ICY
+	// metadata can update the title without a second connection.
+	var stream io.ReadCloser
+	if station := v.liveStation(); station != nil && !isHLSURL(input) {
+		var err error
+		stream, err = v.openStationStream(station, input)
+		if err != nil {
+			return nil, err
+		}
+		input = "pipe:0"
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "quiet"}
+	if isRemoteInput(input) {
+		args = append(args, "-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5")
+		// A live stream hitting EOF means the connection dropped, not that
+		// the stream is over.
+		if live {
+			args = append(args, "-reconnect_at_eof", "1", "-reconnect_on_network_error", "1")
+		}
+	}
+	// Live streams cannot be seeked; restarting picks them up where they are.
+	if offset > 0 && !live {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", offset.Seconds()))
+	}
+	args = append(args, "-i", input)
+
+	graph, tempo := v.filterGraph()
+	if graph != "" {
+		args = append(args, "-af", graph)
+	}
+	args = append(args, "-f", "s16le", "-ar", "48000", "-ac", "2", "pipe:1")
+
+	ffmpeg := exec.Command("ffmpeg", args...)
+	if stream != nil {
+		ffmpeg.Stdin = stream
+	}
+	ffmpegout, err := ffmpeg.StdoutPipe()
+	if err != nil {
+		if stream != nil {
+			stream.Close()
+		}
+		logger.Log("FFmpeg pipe error: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Error,
+		})
+		return nil, err
+	}
+
+	ffmpeg.Stderr = nil
+	err = ffmpeg.Start()
+	if err != nil {
+		if stream != nil {
+			stream.Close()
+		}
+		logger.Log("FFmpeg start error: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Error,
+		})
+		return nil, err
+	}
+
+	return &decoder{
+		cmd:    ffmpeg,
+		out:    bufio.NewReaderSize(ffmpegout, maxBytes*4),
+		stream: stream,
+		tempo:  tempo,
+	}, nil
+}
+
+// readFrame fills buf with the next frame. It returns io.EOF once the input
+// is exhausted.
+func (d *decoder) readFrame(buf []int16) error {
+	err := binary.Read(d.out, binary.LittleEndian, buf)
+	if err == io.ErrUnexpectedEOF {
+		return io.EOF
+	}
+	return err
+}
+
+// frameAdvance is how much source time one output frame covers.
+func (d *decoder) frameAdvance() time.Duration {
+	return time.Duration(float64(frameDuration) * d.tempo)
+}
+
+func (d *decoder) close() {
+	d.closeOnce.Do(func() {
+		d.cmd.Process.Kill()
+		// Wait also waits for stdin to be copied, which only stops once the
+		// station connection is closed.
+		if d.stream != nil {
+			d.stream.Close()
+		}
+		d.cmd.Wait()
+	})
+}
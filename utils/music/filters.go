@@ -0,0 +1,190 @@
+package music
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type filterPreset struct {
+	Name  string
+	Graph string
+	// Tempo is how much faster than real time the preset plays the source,
+	// used to keep the reported position in source time.
+	Tempo float64
+	// Group marks presets that cannot be combined, such as two presets that
+	// both resample the stream.
+	Group string
+}
+
+var filterPresets = []filterPreset{
+	{Name: "bassboost", Graph: "bass=g=10:f=110:w=0.6", Tempo: 1},
+	{Name: "nightcore", Graph: "aresample=48000,asetrate=48000*1.25,aresample=48000", Tempo: 1.25, Group: "rate"},
+	{Name: "vaporwave", Graph: "aresample=48000,asetrate=48000*0.8,aresample=48000", Tempo: 0.8, Group: "rate"},
+	{Name: "8d", Graph: "apulsator=hz=0.08", Tempo: 1},
+	{Name: "karaoke", Graph: "pan=stereo|c0=c0-c1|c1=c1-c0", Tempo: 1},
+	{Name: "treble", Graph: "treble=g=5", Tempo: 1},
+	{Name: "mono", Graph: "pan=mono|c0=0.5*c0+0.5*c1", Tempo: 1},
+}
+
+// EqualizerBand boosts or cuts the audio around a centre frequency.
+type EqualizerBand struct {
+	Frequency int
+	Gain      float64
+}
+
+// FilterPresetNames lists the names accepted by ToggleFilter.
+func FilterPresetNames() []string {
+	names := make([]string, 0, len(filterPresets))
+	for _, preset := range filterPresets {
+		names = append(names, preset.Name)
+	}
+	return names
+}
+
+func findFilterPreset(name string) (filterPreset, bool) {
+	for _, preset := range filterPresets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return filterPreset{}, false
+}
+
+// ParseEqualizer reads bands written as "frequency:gain" pairs separated by
+// commas or spaces, for example "60:+6, 250:-2, 4000:3".
+func ParseEqualizer(input string) ([]EqualizerBand, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no equalizer bands given")
+	}
+
+	bands := make([]EqualizerBand, 0, len(fields))
+	for _, field := range fields {
+		parts := strings.Split(field, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("band %q must look like frequency:gain", field)
+		}
+
+		frequency, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(parts[0]), "hz"))
+		if err != nil || frequency < 20 || frequency > 20000 {
+			return nil, fmt.Errorf("frequency in %q must be between 20 and 20000 Hz", field)
+		}
+
+		gain, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(parts[1]), "db"), 64)
+		if err != nil || gain < -20 || gain > 20 {
+			return nil, fmt.Errorf("gain in %q must be between -20 and 20 dB", field)
+		}
+
+		bands = append(bands, EqualizerBand{Frequency: frequency, Gain: gain})
+	}
+
+	return bands, nil
+}
+
+// ToggleFilter enables a preset, or disables it when it is already active.
+// Enabling a preset drops any active preset that cannot be combined with it.
+// It returns whether the preset is now active.
+func (v *VoiceInstance) ToggleFilter(name string) (bool, error) {
+	preset, ok := findFilterPreset(name)
+	if !ok {
+		return false, fmt.Errorf("unknown filter: %s", name)
+	}
+
+	v.mu.Lock()
+	enabled := !slices.Contains(v.Filters, name)
+	if enabled {
+		active := v.Filters[:0:0]
+		for _, filterName := range v.Filters {
+			other, _ := findFilterPreset(filterName)
+			if preset.Group == "" || other.Group != preset.Group {
+				active = append(active, filterName)
+			}
+		}
+		v.Filters = append(active, name)
+	} else {
+		v.Filters = slices.DeleteFunc(slices.Clone(v.Filters), func(filterName string) bool {
+			return filterName == name
+		})
+	}
+	v.mu.Unlock()
+
+	v.restartDecode()
+	return enabled, nil
+}
+
+// SetEqualizer replaces the custom equalizer bands. Passing no bands turns
+// the equalizer off.
+func (v *VoiceInstance) SetEqualizer(bands []EqualizerBand) {
+	v.mu.Lock()
+	v.Equalizer = bands
+	v.mu.Unlock()
+
+	v.restartDecode()
+}
+
+// ClearFilters disables every preset and the custom equalizer.
+func (v *VoiceInstance) ClearFilters() {
+	v.mu.Lock()
+	v.Filters = nil
+	v.Equalizer = nil
+	v.mu.Unlock()
+
+	v.restartDecode()
+}
+
+// ActiveFilters describes the enabled presets and equalizer for display.
+func (v *VoiceInstance) ActiveFilters() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	active := slices.Clone(v.Filters)
+	if len(v.Equalizer) > 0 {
+		bands := make([]string, 0, len(v.Equalizer))
+		for _, band := range v.Equalizer {
+			bands = append(bands, fmt.Sprintf("%dHz %+gdB", band.Frequency, band.Gain))
+		}
+		active = append(active, "eq("+strings.Join(bands, ", ")+")")
+	}
+	return active
+}
+
+// filterGraph builds the ffmpeg -af argument for the active filters together
+// with the combined tempo of the chain.
+func (v *VoiceInstance) filterGraph() (string, float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	stages := []string{}
+	tempo := 1.0
+
+	for _, name := range v.Filters {
+		preset, ok := findFilterPreset(name)
+		if !ok {
+			continue
+		}
+		stages = append(stages, preset.Graph)
+		tempo *= preset.Tempo
+	}
+
+	for _, band := range v.Equalizer {
+		stages = append(stages, fmt.Sprintf("equalizer=f=%d:t=o:w=1:g=%g", band.Frequency, band.Gain))
+	}
+
+	return strings.Join(stages, ","), tempo
+}
+
+// restartDecode re-spawns ffmpeg at the current position so filter changes
+// apply to the playing track. Unlike a seek this also works for live
+// streams, which are picked up where they are.
+func (v *VoiceInstance) restartDecode() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.Playing {
+		v.requestSeekLocked(v.Position())
+	}
+}
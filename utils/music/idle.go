@@ -0,0 +1,159 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SetStayConnected toggles 24/7 mode for the guild, which keeps the bot in
+// its voice channel regardless of the idle and empty-channel timeouts.
+func (v *VoiceInstance) SetStayConnected(enabled bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.StayConnected = enabled
+	if enabled {
+		stopTimer(v.idleTimer)
+		stopTimer(v.emptyTimer)
+		v.idleTimer, v.emptyTimer = nil, nil
+	} else if !v.queueRunning {
+		v.startIdleTimerLocked()
+	}
+}
+
+// IsStayConnected reports whether 24/7 mode is on for this guild.
+func (v *VoiceInstance) IsStayConnected() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.StayConnected
+}
+
+// startIdleTimerLocked schedules a disconnect after the configured time
+// without playback. The caller must hold v.mu.
+func (v *VoiceInstance) startIdleTimerLocked() {
+	stopTimer(v.idleTimer)
+	v.idleTimer = nil
+
+	if v.StayConnected || config.Config.AlwaysConnected || config.Config.IdleTimeout <= 0 {
+		return
+	}
+
+	timeout := time.Duration(config.Config.IdleTimeout) * time.Second
+	v.idleTimer = time.AfterFunc(timeout, func() {
+		v.autoDisconnect(fmt.Sprintf("nothing was played for %s", FormatDuration(timeout)))
+	})
+}
+
+// stopIdleTimerLocked cancels a pending idle disconnect. The caller must
+// hold v.mu.
+func (v *VoiceInstance) stopIdleTimerLocked() {
+	stopTimer(v.idleTimer)
+	v.idleTimer = nil
+}
+
+// CheckListeners starts the empty-channel timer when nobody but bots is left
+// in the bot's voice channel and cancels it when someone comes back.
+func (v *VoiceInstance) CheckListeners(s *discordgo.Session) {
+	listeners := countListeners(s, v.GuildID, v.VoiceChannelID())
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if listeners > 0 {
+		stopTimer(v.emptyTimer)
+		v.emptyTimer = nil
+		return
+	}
+
+	if v.emptyTimer != nil || v.StayConnected || config.Config.AlwaysConnected || config.Config.EmptyChannelTimeout <= 0 {
+		return
+	}
+
+	timeout := time.Duration(config.Config.EmptyChannelTimeout) * time.Second
+	v.emptyTimer = time.AfterFunc(timeout, func() {
+		v.autoDisconnect("everyone left the voice channel")
+	})
+}
+
+func (v *VoiceInstance) stopTimers() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	stopTimer(v.idleTimer)
+	stopTimer(v.emptyTimer)
+	v.idleTimer, v.emptyTimer = nil, nil
+}
+
+func (v *VoiceInstance) autoDisconnect(reason string) {
+	v.mu.Lock()
+	session := v.Session
+	stay := v.StayConnected
+	v.mu.Unlock()
+
+	if stay || config.Config.AlwaysConnected {
+		return
+	}
+
+	if !v.Active() {
+		return
+	}
+
+	channelName := "the voice channel"
+	if session != nil {
+		if channel, err := session.State.Channel(v.VoiceChannelID()); err == nil {
+			channelName = "**" + channel.Name + "**"
+		}
+	}
+
+	logger.Log(fmt.Sprintf("Leaving voice channel in guild %s because %s", v.GuildID, reason), types.LogOptions{
+		Prefix: "Music Player",
+		Level:  types.Info,
+	})
+
+	if err := LeaveVoiceChannel(v.GuildID); err != nil {
+		logger.Log("Failed to leave voice channel: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Error,
+		})
+		return
+	}
+
+	v.notify(fmt.Sprintf("👋 Left %s because %s.", channelName, reason))
+}
+
+// countListeners returns how many non-bot users are in a voice channel.
+func countListeners(s *discordgo.Session, guildID, channelID string) int {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		return 0
+	}
+
+	listeners := 0
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != channelID || vs.UserID == s.State.User.ID {
+			continue
+		}
+
+		if vs.Member != nil && vs.Member.User != nil && vs.Member.User.Bot {
+			continue
+		}
+		if member, err := s.State.Member(guildID, vs.UserID); err == nil && member.User != nil && member.User.Bot {
+			continue
+		}
+
+		listeners++
+	}
+	return listeners
+}
+
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
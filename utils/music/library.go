@@ -0,0 +1,315 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var libraryExtensions = []string{".mp3", ".flac", ".ogg", ".opus", ".m4a", ".aac", ".wav"}
+
+type libraryEntry struct {
+	ID       string
+	Path     string
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+	Size     int64
+	ModTime  time.Time
+	search   string
+}
+
+// localLibrary indexes the audio files under the configured library
+// directory by their tags. The directory is rescanned periodically and only
+// new or modified files are probed again.
+type localLibrary struct {
+	mu      sync.RWMutex
+	dir     string
+	entries map[string]*libraryEntry
+	byPath  map[string]*libraryEntry
+}
+
+var LocalLibrary = &localLibrary{
+	entries: make(map[string]*libraryEntry),
+	byPath:  make(map[string]*libraryEntry),
+}
+
+// LoadLocalLibrary indexes the library directory in the background and keeps
+// rescanning it for changes. It does nothing when LIBRARY_DIR is unset.
+func LoadLocalLibrary() {
+	dir := config.Config.LibraryDir
+	if dir == "" {
+		return
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+
+	LocalLibrary.mu.Lock()
+	LocalLibrary.dir = absDir
+	LocalLibrary.mu.Unlock()
+
+	go func() {
+		LocalLibrary.scan()
+
+		interval := time.Duration(config.Config.LibraryScanInterval) * time.Second
+		if interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			LocalLibrary.scan()
+		}
+	}()
+}
+
+// Enabled reports whether a library directory is configured.
+func (l *localLibrary) Enabled() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.dir != ""
+}
+
+// scan walks the library directory, probing files that are new or changed
+// since the last scan and dropping entries whose files are gone.
+func (l *localLibrary) scan() {
+	l.mu.RLock()
+	dir := l.dir
+	known := make(map[string]*libraryEntry, len(l.byPath))
+	for path, entry := range l.byPath {
+		known[path] = entry
+	}
+	l.mu.RUnlock()
+
+	found := make(map[string]*libraryEntry)
+	probed := 0
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !slices.Contains(libraryExtensions, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		if entry, ok := known[path]; ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			found[path] = entry
+			return nil
+		}
+
+		entry, err := probeLibraryFile(dir, path)
+		if err != nil {
+			logger.Log("Failed to read tags from "+path+": "+err.Error(), types.LogOptions{
+				Prefix: "Local Library",
+				Level:  types.Warn,
+			})
+			return nil
+		}
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+
+		found[path] = entry
+		probed++
+		return nil
+	})
+	if err != nil {
+		logger.Log("Failed to scan library directory: "+err.Error(), types.LogOptions{
+			Prefix: "Local Library",
+			Level:  types.Error,
+		})
+		return
+	}
+
+	removed := 0
+	for path := range known {
+		if _, ok := found[path]; !ok {
+			removed++
+		}
+	}
+
+	entries := make(map[string]*libraryEntry, len(found))
+	for _, entry := range found {
+		entries[entry.ID] = entry
+	}
+
+	l.mu.Lock()
+	l.byPath = found
+	l.entries = entries
+	l.mu.Unlock()
+
+	if probed > 0 || removed > 0 {
+		logger.Log(fmt.Sprintf("Local library indexed: %d track(s), %d new or changed, %d removed", len(found), probed, removed), types.LogOptions{
+			Prefix: "Local Library",
+			Level:  types.Success,
+		})
+	}
+}
+
+// probeLibraryFile reads a file's duration and tags with ffprobe. ID3,
+// Vorbis comment and MP4 tags all come back as format tags, only differing
+// in case.
+func probeLibraryFile(dir, path string) (*libraryEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	output, err := ffprobeCommand(ctx, "-show_entries", "format=duration:format_tags", "-of", "json", path).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for key, value := range probe.Format.Tags {
+		tags[strings.ToLower(key)] = strings.TrimSpace(value)
+	}
+
+	relative, err := filepath.Rel(dir, path)
+	if err != nil {
+		relative = path
+	}
+	hash := sha256.Sum256([]byte(relative))
+
+	entry := &libraryEntry{
+		ID:     hex.EncodeToString(hash[:8]),
+		Path:   path,
+		Title:  tags["title"],
+		Artist: tags["artist"],
+		Album:  tags["album"],
+	}
+
+	if entry.Artist == "" {
+		entry.Artist = tags["album_artist"]
+	}
+	if entry.Title == "" {
+		entry.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if seconds, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		entry.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	entry.search = strings.ToLower(strings.Join([]string{entry.Title, entry.Artist, entry.Album, relative}, " "))
+	return entry, nil
+}
+
+// SearchLocal matches the query against the library's titles, artists,
+// albums and file paths. Every word of the query has to match, and tracks
+// whose title starts with the query are listed first.
+func SearchLocal(query string, limit int) ([]types.MusicSearchResult, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	LocalLibrary.mu.RLock()
+	matches := []*libraryEntry{}
+	for _, entry := range LocalLibrary.entries {
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(entry.search, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entry)
+		}
+	}
+	LocalLibrary.mu.RUnlock()
+
+	lowerQuery := strings.ToLower(query)
+	sort.Slice(matches, func(a, b int) bool {
+		prefixA := strings.HasPrefix(strings.ToLower(matches[a].Title), lowerQuery)
+		prefixB := strings.HasPrefix(strings.ToLower(matches[b].Title), lowerQuery)
+		if prefixA != prefixB {
+			return prefixA
+		}
+		return matches[a].Title < matches[b].Title
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := make([]types.MusicSearchResult, 0, len(matches))
+	for _, entry := range matches {
+		results = append(results, entry.result())
+	}
+
+	return results, nil
+}
+
+func GetLocalInfoByID(id string) (types.MusicSearchResult, error) {
+	entry, err := LocalLibrary.lookup(id)
+	if err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	return entry.result(), nil
+}
+
+// lookup returns the indexed entry for id after making sure its file still
+// exists inside the library directory. Playback only ever uses paths that
+// come from here, never ones supplied with a command.
+func (l *localLibrary) lookup(id string) (*libraryEntry, error) {
+	l.mu.RLock()
+	entry, ok := l.entries[id]
+	dir := l.dir
+	l.mu.RUnlock()
+
+	if !ok || dir == "" {
+		return nil, fmt.Errorf("track not found in the local library")
+	}
+
+	relative, err := filepath.Rel(dir, entry.Path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) || filepath.IsAbs(relative) {
+		return nil, fmt.Errorf("track is outside the library directory")
+	}
+
+	if _, err := os.Stat(entry.Path); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (entry *libraryEntry) result() types.MusicSearchResult {
+	return types.MusicSearchResult{
+		Title:      entry.Title,
+		Artist:     entry.Artist,
+		ID:         entry.ID,
+		Duration:   FormatDuration(entry.Duration),
+		SourceType: types.Local,
+	}
+}
@@ -0,0 +1,129 @@
+package music
+
+import (
+	"ai/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLiveRestarts is how many times in a row a live stream's decoder is
+	// restarted after its input ends before the stream is considered over.
+	maxLiveRestarts   = 5
+	liveRestartDelay  = 3 * time.Second
+	liveRestartWindow = time.Minute
+)
+
+var twitchRegex = regexp.MustCompile(`^(https?://)?((www|m)\.)?twitch\.tv/.+`)
+
+// BroadcastError explains why a livestream cannot be played: it has not
+// started yet, or it is over and there is nothing to play back.
+type BroadcastError struct {
+	Upcoming       bool
+	ScheduledStart time.Time
+}
+
+func (e *BroadcastError) Error() string {
+	if !e.Upcoming {
+		return "This broadcast is offline or has ended, and there is no recording to play yet."
+	}
+	if e.ScheduledStart.IsZero() {
+		return "This livestream hasn't started yet."
+	}
+	return fmt.Sprintf("This livestream hasn't started yet. It is scheduled to start <t:%d:R>.", e.ScheduledStart.Unix())
+}
+
+func IsTwitchURL(input string) bool {
+	return twitchRegex.MatchString(input)
+}
+
+// GetTwitchInfo looks up a Twitch channel or VOD with yt-dlp. Channels are
+// only playable while they are live.
+func GetTwitchInfo(twitchURL string) (types.MusicSearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	output, err := ytdlpCommand(ctx, "--no-playlist", "--dump-single-json", "--", twitchURL).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.Contains(string(exitErr.Stderr), "not currently live") {
+			return types.MusicSearchResult{}, &BroadcastError{}
+		}
+		return types.MusicSearchResult{}, fmt.Errorf("yt-dlp could not resolve Twitch URL: %w", err)
+	}
+
+	var info struct {
+		ID          string  `json:"id"`
+		Title       string  `json:"title"`
+		Description string  `json:"description"`
+		Uploader    string  `json:"uploader"`
+		IsLive      bool    `json:"is_live"`
+		Duration    float64 `json:"duration"`
+		Thumbnail   string  `json:"thumbnail"`
+		WebpageURL  string  `json:"webpage_url"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	result := types.MusicSearchResult{
+		Title:      info.Title,
+		Artist:     info.Uploader,
+		URL:        info.WebpageURL,
+		ID:         info.ID,
+		Thumbnail:  info.Thumbnail,
+		SourceType: types.Twitch,
+		Live:       info.IsLive,
+	}
+
+	if result.URL == "" {
+		result.URL = twitchURL
+	}
+
+	// For live channels yt-dlp titles the stream after the channel and date,
+	// while the description holds the title the streamer set.
+	if info.IsLive && info.Description != "" {
+		result.Title = info.Description
+	}
+
+	if !info.IsLive {
+		result.Duration = FormatDuration(time.Duration(info.Duration * float64(time.Second)))
+	}
+
+	return result, nil
+}
+
+// liveBroadcastError checks the live state the YouTube Data API reports for
+// a video. Upcoming streams and finished streams without a processed
+// recording cannot be played.
+func liveBroadcastError(liveBroadcastContent, scheduledStart, actualEnd, duration string) error {
+	if liveBroadcastContent == "upcoming" {
+		start, _ := time.Parse(time.RFC3339, scheduledStart)
+		return &BroadcastError{Upcoming: true, ScheduledStart: start}
+	}
+
+	if liveBroadcastContent == "none" && actualEnd != "" && (duration == "" || duration == "P0D") {
+		return &BroadcastError{}
+	}
+
+	return nil
+}
+
+// sleepOrStop waits for d, returning false early if playback is stopped.
+func sleepOrStop(d time.Duration, stopChan chan bool) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stopChan:
+		return false
+	}
+}
@@ -0,0 +1,191 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// matchCandidates is how many YouTube results are scored when looking for
+// the video that plays a Spotify track.
+const matchCandidates = 8
+
+// versionKeywords mark alternative versions of a song. Candidates using them
+// are penalised unless the Spotify title asks for that version too.
+var versionKeywords = []string{
+	"live", "cover", "remix", "sped up", "slowed", "nightcore", "karaoke",
+	"instrumental", "acoustic", "reverb", "8d", "extended", "mashup",
+}
+
+type matchScore struct {
+	score   float64
+	reasons []string
+}
+
+func (m *matchScore) add(points float64, reason string) {
+	m.score += points
+	m.reasons = append(m.reasons, fmt.Sprintf("%s %+.0f", reason, points))
+}
+
+// GetYouTubeForSpotify finds the YouTube video that best matches a Spotify
+// track. Several candidates are scored on how close their duration is, who
+// uploaded them and whether their title suggests a different version.
+func GetYouTubeForSpotify(track types.MusicSearchResult) (types.MusicSearchResult, error) {
+	query := fmt.Sprintf("%s %s", track.Title, track.Artist)
+
+	candidates, err := SearchYouTube(context.Background(), query, matchCandidates)
+	if err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	if len(candidates) == 0 {
+		return types.MusicSearchResult{}, fmt.Errorf("no YouTube results found")
+	}
+
+	durations := candidateDurations(candidates)
+	target := parseDisplayDuration(track.Duration)
+
+	best := -1
+	var bestScore matchScore
+	for index, candidate := range candidates {
+		score := scoreCandidate(track, candidate, durations[candidate.ID], target, index, len(candidates))
+		if best == -1 || score.score > bestScore.score {
+			best, bestScore = index, score
+		}
+	}
+
+	winner := candidates[best]
+	logger.Log(fmt.Sprintf("Matched %q by %s to %q by %s (score %.0f: %s)", track.Title, track.Artist,
+		winner.Title, winner.Artist, bestScore.score, strings.Join(bestScore.reasons, ", ")), types.LogOptions{
+		Prefix: "Search",
+		Level:  types.Info,
+	})
+
+	return winner, nil
+}
+
+func scoreCandidate(track, candidate types.MusicSearchResult, duration, target time.Duration, rank, total int) matchScore {
+	var score matchScore
+
+	// Earlier results are slightly preferred, which only matters for ties.
+	score.add(float64(total-rank), fmt.Sprintf("rank %d", rank+1))
+
+	if duration > 0 && target > 0 {
+		diff := (duration - target).Abs()
+		switch {
+		case diff <= 2*time.Second:
+			score.add(30, "duration within 2s")
+		case diff <= 5*time.Second:
+			score.add(20, fmt.Sprintf("duration off by %s", diff.Round(time.Second)))
+		case diff <= 15*time.Second:
+			score.add(5, fmt.Sprintf("duration off by %s", diff.Round(time.Second)))
+		default:
+			score.add(-min(diff.Seconds()/2, 40), fmt.Sprintf("duration off by %s", diff.Round(time.Second)))
+		}
+	}
+
+	channel := normalizeWords(candidate.Artist)
+	artist := normalizeWords(track.Artist)
+	switch {
+	case strings.HasSuffix(candidate.Artist, " - Topic"):
+		score.add(25, "topic channel")
+	case strings.TrimSpace(artist) != "" && strings.Contains(channel, artist):
+		score.add(15, "artist channel")
+	case strings.HasSuffix(strings.ToLower(strings.TrimSpace(candidate.Artist)), "vevo"):
+		score.add(10, "vevo channel")
+	}
+
+	title := normalizeWords(candidate.Title)
+	wanted := normalizeWords(track.Title)
+
+	words := strings.Fields(wanted)
+	if len(words) > 0 {
+		matched := 0
+		for _, word := range words {
+			if strings.Contains(title, " "+word+" ") {
+				matched++
+			}
+		}
+		score.add(20*float64(matched)/float64(len(words)), fmt.Sprintf("title words %d/%d", matched, len(words)))
+	}
+
+	for _, keyword := range versionKeywords {
+		phrase := " " + keyword + " "
+		if strings.Contains(title, phrase) && !strings.Contains(wanted, phrase) {
+			score.add(-20, fmt.Sprintf("%q version", keyword))
+		}
+	}
+
+	if strings.Contains(title, " official audio ") {
+		score.add(5, "official audio")
+	}
+
+	if candidate.Live {
+		score.add(-50, "livestream")
+	}
+
+	return score
+}
+
+// candidateDurations returns the length of each candidate. Search results
+// from the Data API carry no duration, so those are looked up in one videos
+// request; failures only mean durations are left out of the scoring.
+func candidateDurations(candidates []types.MusicSearchResult) map[string]time.Duration {
+	durations := make(map[string]time.Duration, len(candidates))
+
+	ids := []string{}
+	for _, candidate := range candidates {
+		if duration := parseDisplayDuration(candidate.Duration); duration > 0 {
+			durations[candidate.ID] = duration
+		} else {
+			ids = append(ids, candidate.ID)
+		}
+	}
+
+	if len(ids) == 0 || !youtubeAPIAvailable() {
+		return durations
+	}
+
+	apiURL := fmt.Sprintf(
+		"https://www.googleapis.com/youtube/v3/videos?part=contentDetails&id=%s&key=%s",
+		url.QueryEscape(strings.Join(ids, ",")), config.Config.YoutubeAPIKey,
+	)
+
+	var response struct {
+		Items []struct {
+			ID             string `json:"id"`
+			ContentDetails struct {
+				Duration string `json:"duration"`
+			} `json:"contentDetails"`
+		} `json:"items"`
+	}
+
+	if err := youtubeGet(apiURL, &response); err != nil {
+		logger.Log("Failed to fetch candidate durations: "+err.Error(), types.LogOptions{
+			Prefix: "Search",
+			Level:  types.Warn,
+		})
+		return durations
+	}
+
+	for _, item := range response.Items {
+		durations[item.ID] = parseDisplayDuration(item.ContentDetails.Duration)
+	}
+
+	return durations
+}
+
+// normalizeWords lowercases text and replaces punctuation with spaces,
+// padding the result so whole words can be matched as " word ".
+func normalizeWords(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
@@ -0,0 +1,356 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	nowPlayingInterval = 10 * time.Second
+	progressBarWidth   = 16
+
+	PlayerButtonPrefix  = "player"
+	PlayerPauseButton   = "player:pause"
+	PlayerSkipButton    = "player:skip"
+	PlayerStopButton    = "player:stop"
+	PlayerLoopButton    = "player:loop"
+	PlayerShuffleButton = "player:shuffle"
+)
+
+// twemojiIcon is the URL of an emoji image, used as the icon of sources
+// without a logo of their own.
+const twemojiIcon = "https://cdn.jsdelivr.net/gh/twitter/twemoji@14.0.2/assets/72x72/%s.png"
+
+var (
+	sourceIcons = map[types.SourceType]string{
+		types.YouTube:    "https://www.gstatic.com/youtube/img/branding/favicon/favicon_144x144.png",
+		types.Spotify:    "https://storage.googleapis.com/pr-newsroom-wp/1/2023/05/Spotify_Primary_Logo_RGB_Green.png",
+		types.SoundCloud: fmt.Sprintf(twemojiIcon, "2601"),
+		types.HTTP:       fmt.Sprintf(twemojiIcon, "1f310"),
+		types.Attachment: fmt.Sprintf(twemojiIcon, "1f4ce"),
+		types.Local:      fmt.Sprintf(twemojiIcon, "1f4c1"),
+		types.Twitch:     fmt.Sprintf(twemojiIcon, "1f4fa"),
+	}
+
+	radioIcon   = fmt.Sprintf(twemojiIcon, "1f4fb")
+	defaultIcon = fmt.Sprintf(twemojiIcon, "1f3b5")
+
+	sourceNames = map[types.SourceType]string{
+		types.YouTube:    "YouTube",
+		types.Spotify:    "Spotify",
+		types.SoundCloud: "SoundCloud",
+		types.HTTP:       "Web audio",
+		types.Attachment: "Discord upload",
+		types.Local:      "Local library",
+		types.Twitch:     "Twitch",
+	}
+
+	sourceColors = map[types.SourceType]int{
+		types.YouTube:    0xFF0000,
+		types.Spotify:    0x1DB954,
+		types.SoundCloud: 0xFF5500,
+		types.HTTP:       0x5865F2,
+		types.Attachment: 0x5865F2,
+		types.Local:      0x95A5A6,
+		types.Twitch:     0x9146FF,
+	}
+)
+
+type nowPlayingMessage struct {
+	channelID string
+	messageID string
+}
+
+// SetTextChannel remembers where playback was last controlled from so the
+// player can post its own messages there.
+func (v *VoiceInstance) SetTextChannel(channelID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.TextChannelID = channelID
+}
+
+// SetNowPlayingMessage makes the given message the one that receives live
+// progress updates for the current track.
+func (v *VoiceInstance) SetNowPlayingMessage(channelID, messageID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.nowPlaying = &nowPlayingMessage{channelID: channelID, messageID: messageID}
+}
+
+// NowPlayingEmbed renders the current track with its artwork, requester,
+// source and a progress bar. It returns nil when nothing is playing.
+func (v *VoiceInstance) NowPlayingEmbed() *discordgo.MessageEmbed {
+	v.mu.Lock()
+	var track *types.Track
+	if v.CurrentTrack != nil {
+		// Copy the track so stream metadata updates cannot race the render.
+		current := *v.CurrentTrack
+		track = &current
+	}
+	duration := v.TrackDuration
+	paused := v.Paused
+	volume := v.Volume
+	upcoming := len(v.Queue)
+	v.mu.Unlock()
+
+	if track == nil {
+		return nil
+	}
+
+	return buildNowPlayingEmbed(track, v.Position(), duration, paused, volume, upcoming, v.ActiveFilters(), false)
+}
+
+// NowPlayingComponents returns the player control buttons for the
+// now-playing message, reflecting the pause and loop state.
+func (v *VoiceInstance) NowPlayingComponents() []discordgo.MessageComponent {
+	v.mu.Lock()
+	paused := v.Paused
+	loop := v.Loop
+	v.mu.Unlock()
+
+	pauseButton := discordgo.Button{
+		Emoji:    &discordgo.ComponentEmoji{Name: "⏸️"},
+		Style:    discordgo.SecondaryButton,
+		CustomID: PlayerPauseButton,
+	}
+	if paused {
+		pauseButton.Emoji = &discordgo.ComponentEmoji{Name: "▶️"}
+		pauseButton.Style = discordgo.SuccessButton
+	}
+
+	loopButton := discordgo.Button{
+		Emoji:    &discordgo.ComponentEmoji{Name: "🔁"},
+		Style:    discordgo.SecondaryButton,
+		CustomID: PlayerLoopButton,
+	}
+	switch loop {
+	case types.LoopTrack:
+		loopButton.Emoji = &discordgo.ComponentEmoji{Name: "🔂"}
+		loopButton.Style = discordgo.SuccessButton
+	case types.LoopQueue:
+		loopButton.Style = discordgo.SuccessButton
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				pauseButton,
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "⏭️"},
+					Style:    discordgo.SecondaryButton,
+					CustomID: PlayerSkipButton,
+				},
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "⏹️"},
+					Style:    discordgo.DangerButton,
+					CustomID: PlayerStopButton,
+				},
+				loopButton,
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔀"},
+					Style:    discordgo.SecondaryButton,
+					CustomID: PlayerShuffleButton,
+				},
+			},
+		},
+	}
+}
+
+func buildNowPlayingEmbed(track *types.Track, position, duration time.Duration, paused bool, volume, upcoming int, filters []string, finished bool) *discordgo.MessageEmbed {
+	if duration == 0 {
+		duration = parseDisplayDuration(track.Duration)
+	}
+
+	status := "▶️"
+	if paused {
+		status = "⏸️"
+	}
+
+	var description strings.Builder
+	if track.Artist != "" {
+		description.WriteString(fmt.Sprintf("by **%s**\n\n", track.Artist))
+	}
+
+	if finished {
+		description.WriteString("⏹️ Finished playing")
+	} else if track.Live {
+		description.WriteString(fmt.Sprintf("%s 🔴 **LIVE** `%s`", status, FormatDuration(position)))
+	} else if duration > 0 {
+		description.WriteString(fmt.Sprintf("%s %s `%s / %s`", status, progressBar(position, duration),
+			FormatDuration(position), FormatDuration(duration)))
+	} else {
+		description.WriteString(fmt.Sprintf("%s `%s`", status, FormatDuration(position)))
+	}
+
+	fields := []*discordgo.MessageEmbedField{}
+	if track.RequestedBy != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "Requested by",
+			Value:  fmt.Sprintf("<@%s>", track.RequestedBy),
+			Inline: true,
+		})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   "Volume",
+		Value:  fmt.Sprintf("%d%%", volume),
+		Inline: true,
+	})
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   "Up next",
+		Value:  fmt.Sprintf("%d track(s)", upcoming),
+		Inline: true,
+	})
+	if len(filters) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Filters",
+			Value: strings.Join(filters, ", "),
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       track.Title,
+		Description: description.String(),
+		Color:       sourceColors[track.SourceType],
+		Fields:      fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text:    sourceNames[track.SourceType],
+			IconURL: sourceIcon(track),
+		},
+	}
+
+	// Local tracks have no URL to link to.
+	if isRemoteInput(track.URL) {
+		embed.URL = track.URL
+	}
+
+	if track.Thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: track.Thumbnail}
+	}
+
+	return embed
+}
+
+func progressBar(position, duration time.Duration) string {
+	filled := 0
+	if duration > 0 {
+		filled = int(float64(progressBarWidth) * float64(position) / float64(duration))
+	}
+	filled = min(max(filled, 0), progressBarWidth-1)
+
+	return strings.Repeat("▬", filled) + "🔘" + strings.Repeat("▬", progressBarWidth-filled-1)
+}
+
+// parseDisplayDuration reads the durations carried by search results, either
+// mm:ss / h:mm:ss or ISO 8601 such as PT3M45S.
+func parseDisplayDuration(value string) time.Duration {
+	if strings.HasPrefix(value, "PT") {
+		duration, err := time.ParseDuration(strings.ToLower(strings.TrimPrefix(value, "PT")))
+		if err != nil {
+			return 0
+		}
+		return duration
+	}
+
+	var total time.Duration
+	for _, part := range strings.Split(value, ":") {
+		var number int
+		if _, err := fmt.Sscanf(part, "%d", &number); err != nil {
+			return 0
+		}
+		total = total*60 + time.Duration(number)
+	}
+	return total * time.Second
+}
+
+// announceTrack posts the now-playing message for a track and keeps editing
+// it with the playback progress until done is closed.
+func (v *VoiceInstance) announceTrack(track *types.Track, done chan struct{}) {
+	v.mu.Lock()
+	session := v.Session
+	channelID := v.TextChannelID
+	v.nowPlaying = nil
+	v.mu.Unlock()
+
+	if session == nil || channelID == "" {
+		return
+	}
+
+	embed := v.NowPlayingEmbed()
+	if embed == nil {
+		return
+	}
+
+	message, err := session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: v.NowPlayingComponents(),
+	})
+	if err != nil {
+		logger.Log("Failed to send now playing message: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Error,
+		})
+		return
+	}
+	v.SetNowPlayingMessage(channelID, message.ID)
+
+	ticker := time.NewTicker(nowPlayingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if embed := v.NowPlayingEmbed(); embed != nil {
+				v.editNowPlaying(session, embed, v.NowPlayingComponents())
+			}
+		case <-done:
+			v.mu.Lock()
+			volume := v.Volume
+			upcoming := len(v.Queue)
+			finished := *track
+			v.mu.Unlock()
+
+			v.editNowPlaying(session, buildNowPlayingEmbed(&finished, 0, 0, false, volume, upcoming, nil, true), []discordgo.MessageComponent{})
+			return
+		}
+	}
+}
+
+func (v *VoiceInstance) editNowPlaying(session *discordgo.Session, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) {
+	v.mu.Lock()
+	message := v.nowPlaying
+	v.mu.Unlock()
+
+	if message == nil {
+		return
+	}
+
+	_, err := session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    message.channelID,
+		ID:         message.messageID,
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: &components,
+	})
+	if err != nil {
+		logger.Log("Failed to update now playing message: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Debug,
+		})
+	}
+}
+
+func sourceIcon(track *types.Track) string {
+	if track.SourceType == types.HTTP && track.Live {
+		return radioIcon
+	}
+	if icon, ok := sourceIcons[track.SourceType]; ok {
+		return icon
+	}
+	return defaultIcon
+}
@@ -0,0 +1,73 @@
+package music
+
+import "math"
+
+// volumeRampStep is the largest gain change applied within a single frame,
+// so volume changes fade in over a few hundred milliseconds instead of
+// clicking.
+const volumeRampStep = 0.05
+
+// applyGain scales interleaved samples in place, ramping linearly from one
+// gain to the other across the buffer and clipping to the int16 range.
+func applyGain(buf []int16, from, to float64) {
+	if from == 1 && to == 1 {
+		return
+	}
+
+	step := (to - from) / float64(len(buf))
+	gain := from
+
+	for i, sample := range buf {
+		buf[i] = clampSample(float64(sample) * gain)
+		gain += step
+	}
+}
+
+// rampGain moves the current gain towards the target by at most one ramp step.
+func rampGain(current, target float64) float64 {
+	if math.Abs(target-current) <= volumeRampStep {
+		return target
+	}
+	if target > current {
+		return current + volumeRampStep
+	}
+	return current - volumeRampStep
+}
+
+func clampSample(value float64) int16 {
+	if value > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if value < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(value)
+}
+
+// silenceThreshold is the peak level (about -60 dBFS) below which a frame
+// counts as silent.
+const silenceThreshold = 33
+
+func isSilent(buf []int16) bool {
+	for _, sample := range buf {
+		if sample > silenceThreshold || sample < -silenceThreshold {
+			return false
+		}
+	}
+	return true
+}
+
+// crossfadeFrames mixes the incoming frame into the outgoing one in place
+// using equal-power curves, with the fade progressing from one point to the
+// other (0 to 1) across the buffer.
+func crossfadeFrames(out, in []int16, from, to float64) {
+	step := (to - from) / float64(len(out))
+	progress := from
+
+	for i := range out {
+		fadeOut := math.Cos(progress * math.Pi / 2)
+		fadeIn := math.Sin(progress * math.Pi / 2)
+		out[i] = clampSample(float64(out[i])*fadeOut + float64(in[i])*fadeIn)
+		progress += step
+	}
+}
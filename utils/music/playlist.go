@@ -0,0 +1,237 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	youtubePlaylistPageSize = 50
+	playlistFetchTimeout    = 2 * time.Minute
+)
+
+// YouTubePlaylist is a YouTube playlist or mix. Regular playlists are read
+// through the Data API; mixes and anything else the API refuses are expanded
+// with yt-dlp, whose entries are kept so they are only fetched once.
+type YouTubePlaylist struct {
+	ID      string
+	Name    string
+	entries []types.MusicSearchResult
+}
+
+// youtubeVideoPaths are the URL paths that carry a video ID as their next
+// segment instead of in the query.
+var youtubeVideoPaths = []string{"/shorts/", "/live/", "/embed/"}
+
+// ParseYouTubeURL returns the video and playlist IDs referenced by a YouTube
+// URL. Either may be empty.
+func ParseYouTubeURL(ytURL string) (string, string) {
+	if !strings.Contains(ytURL, "://") {
+		ytURL = "https://" + ytURL
+	}
+
+	parsedURL, err := url.Parse(ytURL)
+	if err != nil {
+		return "", ""
+	}
+
+	query := parsedURL.Query()
+	videoID := query.Get("v")
+	listID := query.Get("list")
+
+	if strings.Contains(parsedURL.Host, "youtu.be") {
+		videoID = strings.Trim(parsedURL.Path, "/")
+	} else {
+		for _, prefix := range youtubeVideoPaths {
+			if rest, found := strings.CutPrefix(parsedURL.Path, prefix); found {
+				videoID, _, _ = strings.Cut(rest, "/")
+				break
+			}
+		}
+	}
+
+	return videoID, listID
+}
+
+// IsYouTubeMix reports whether a playlist ID belongs to an auto-generated mix,
+// which the Data API cannot list.
+func IsYouTubeMix(listID string) bool {
+	return strings.HasPrefix(listID, "RD")
+}
+
+// GetYouTubePlaylist looks up a playlist by ID. videoID is only needed for
+// mixes, which yt-dlp can only expand from the video they were started from.
+func GetYouTubePlaylist(listID, videoID string, limit int) (*YouTubePlaylist, error) {
+	if listID == "" {
+		return nil, fmt.Errorf("could not extract playlist ID from URL")
+	}
+
+	if !IsYouTubeMix(listID) && youtubeAPIAvailable() {
+		name, err := getYouTubePlaylistName(listID)
+		if err == nil {
+			return &YouTubePlaylist{ID: listID, Name: name}, nil
+		}
+
+		logger.Log("YouTube API could not load playlist "+listID+", falling back to yt-dlp: "+err.Error(), types.LogOptions{
+			Prefix: "Search",
+			Level:  types.Warn,
+		})
+	}
+
+	playlistURL := "https://www.youtube.com/playlist?list=" + listID
+	if videoID != "" {
+		playlistURL = fmt.Sprintf("https://www.youtube.com/watch?v=%s&list=%s", videoID, listID)
+	}
+
+	return flatPlaylist(listID, playlistURL, limit)
+}
+
+// FetchTracks calls onPage with each page of playlist videos until the
+// playlist is exhausted, limit videos have been delivered or onPage returns
+// false.
+func (p *YouTubePlaylist) FetchTracks(limit int, onPage func([]types.MusicSearchResult) bool) error {
+	deliver := limitPages(limit, onPage)
+
+	if p.entries != nil {
+		deliver(p.entries)
+		return nil
+	}
+
+	pageToken := ""
+	for {
+		apiURL := fmt.Sprintf(
+			"https://www.googleapis.com/youtube/v3/playlistItems?part=snippet,status&playlistId=%s&maxResults=%d&key=%s",
+			url.QueryEscape(p.ID), youtubePlaylistPageSize, config.Config.YoutubeAPIKey,
+		)
+		if pageToken != "" {
+			apiURL += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+
+		var page types.YouTubePlaylistItemsResponse
+		if err := youtubeGet(apiURL, &page); err != nil {
+			return err
+		}
+
+		tracks := []types.MusicSearchResult{}
+		for _, item := range page.Items {
+			videoID := item.Snippet.ResourceID.VideoID
+			// Deleted and private videos stay in playlists as placeholders.
+			if videoID == "" || item.Status.PrivacyStatus == "private" || item.Snippet.Title == "Deleted video" {
+				continue
+			}
+
+			tracks = append(tracks, types.MusicSearchResult{
+				Title:      item.Snippet.Title,
+				Artist:     strings.TrimSuffix(item.Snippet.VideoOwnerChannelTitle, " - Topic"),
+				URL:        fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
+				ID:         videoID,
+				Duration:   "00:00",
+				Thumbnail:  item.Snippet.Thumbnails.High.URL,
+				SourceType: types.YouTube,
+			})
+		}
+
+		if !deliver(tracks) || page.NextPageToken == "" {
+			return nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func getYouTubePlaylistName(listID string) (string, error) {
+	apiURL := fmt.Sprintf(
+		"https://www.googleapis.com/youtube/v3/playlists?part=snippet&id=%s&key=%s",
+		url.QueryEscape(listID), config.Config.YoutubeAPIKey,
+	)
+
+	var response types.YouTubePlaylistResponse
+	if err := youtubeGet(apiURL, &response); err != nil {
+		return "", err
+	}
+
+	if len(response.Items) == 0 {
+		return "", fmt.Errorf("playlist not found")
+	}
+
+	return response.Items[0].Snippet.Title, nil
+}
+
+// flatPlaylist lists a playlist's videos with yt-dlp without resolving each
+// one, which is quick enough to run while the user waits.
+func flatPlaylist(listID, playlistURL string, limit int) (*YouTubePlaylist, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), playlistFetchTimeout)
+	defer cancel()
+
+	args := []string{"--yes-playlist", "--flat-playlist", "--dump-single-json"}
+	if limit > 0 {
+		args = append(args, "--playlist-end", fmt.Sprint(limit))
+	}
+
+	output, err := ytdlpCommand(ctx, append(args, "--", playlistURL)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp could not load playlist: %w", err)
+	}
+
+	var response struct {
+		Title   string `json:"title"`
+		Entries []struct {
+			ID         string  `json:"id"`
+			Title      string  `json:"title"`
+			Channel    string  `json:"channel"`
+			Uploader   string  `json:"uploader"`
+			Duration   float64 `json:"duration"`
+			Thumbnails []struct {
+				URL string `json:"url"`
+			} `json:"thumbnails"`
+		} `json:"entries"`
+	}
+
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, err
+	}
+
+	playlist := &YouTubePlaylist{
+		ID:      listID,
+		Name:    response.Title,
+		entries: []types.MusicSearchResult{},
+	}
+
+	for _, entry := range response.Entries {
+		if entry.ID == "" {
+			continue
+		}
+
+		artist := entry.Channel
+		if artist == "" {
+			artist = entry.Uploader
+		}
+
+		thumbnail := ""
+		if len(entry.Thumbnails) > 0 {
+			thumbnail = entry.Thumbnails[len(entry.Thumbnails)-1].URL
+		}
+
+		playlist.entries = append(playlist.entries, types.MusicSearchResult{
+			Title:      entry.Title,
+			Artist:     artist,
+			URL:        fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
+			ID:         entry.ID,
+			Duration:   FormatDuration(time.Duration(entry.Duration * float64(time.Second))),
+			Thumbnail:  thumbnail,
+			SourceType: types.YouTube,
+		})
+	}
+
+	if playlist.Name == "" {
+		playlist.Name = "YouTube Mix"
+	}
+
+	return playlist, nil
+}
@@ -0,0 +1,28 @@
+package music
+
+import "testing"
+
+func TestParseYouTubeURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		videoID string
+		listID  string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", ""},
+		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", ""},
+		{"youtube.com/watch?v=dQw4w9WgXcQ&list=PL590L5WQmH8fJ54F369BLDSqIwcs-TCfs", "dQw4w9WgXcQ", "PL590L5WQmH8fJ54F369BLDSqIwcs-TCfs"},
+		{"https://www.youtube.com/playlist?list=PL590L5WQmH8fJ54F369BLDSqIwcs-TCfs", "", "PL590L5WQmH8fJ54F369BLDSqIwcs-TCfs"},
+		{"https://www.youtube.com/shorts/aqz-KE-bpKQ", "aqz-KE-bpKQ", ""},
+		{"https://www.youtube.com/live/jfKfPfyJRdk?si=abc123", "jfKfPfyJRdk", ""},
+		{"https://youtube.com/live/jfKfPfyJRdk/", "jfKfPfyJRdk", ""},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ?start=30", "dQw4w9WgXcQ", ""},
+		{"https://www.youtube.com/@LofiGirl", "", ""},
+	}
+
+	for _, test := range tests {
+		videoID, listID := ParseYouTubeURL(test.url)
+		if videoID != test.videoID || listID != test.listID {
+			t.Errorf("ParseYouTubeURL(%q) = %q, %q, want %q, %q", test.url, videoID, listID, test.videoID, test.listID)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"time"
+)
+
+// prefetchLead is how long before the end of the current track the next one
+// starts resolving. It covers a typical yt-dlp download in download mode.
+const prefetchLead = 30 * time.Second
+
+type prefetchJob struct {
+	track  *types.Track
+	cancel context.CancelFunc
+	done   chan struct{}
+	source *preparedSource
+	err    error
+}
+
+// maybePrefetch starts preparing the head of the queue in the background
+// unless a job for that track is already running. A job for a track that is
+// no longer next in line is cancelled.
+func (v *VoiceInstance) maybePrefetch() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	next := v.nextTrackLocked()
+
+	if v.prefetch != nil {
+		if v.prefetch.track == next {
+			return
+		}
+		v.cancelPrefetchLocked()
+	}
+
+	if next == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &prefetchJob{
+		track:  next,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	v.prefetch = job
+
+	logger.Log("Prefetching next track: "+next.Title, types.LogOptions{
+		Prefix: "Music Player",
+		Level:  types.Debug,
+	})
+
+	go func() {
+		defer close(job.done)
+
+		if job.err = v.resolveTrack(next); job.err != nil {
+			return
+		}
+
+		v.mu.Lock()
+		source, trackURL, trackID, live := next.PlaybackSource(), next.PlaybackURL, next.PlaybackID, next.Live
+		v.mu.Unlock()
+
+		job.source, job.err = prepareSource(ctx, source, trackURL, trackID, live)
+		if job.err != nil || live || job.source.duration > 0 {
+			return
+		}
+
+		// Cached and downloaded files carry no duration. Probing it here
+		// keeps ffprobe out of the gap between two tracks on a handoff.
+		if duration, err := probeDuration(job.source.input); err == nil {
+			job.source.duration = duration
+		}
+	}()
+}
+
+// cancelPrefetchLocked aborts the running prefetch and releases whatever it
+// managed to prepare. The caller must hold v.mu.
+func (v *VoiceInstance) cancelPrefetchLocked() {
+	job := v.prefetch
+	if job == nil {
+		return
+	}
+	v.prefetch = nil
+
+	job.cancel()
+	go func() {
+		<-job.done
+		if job.source != nil {
+			job.source.release()
+		}
+	}()
+}
+
+// takePrefetched hands over the prefetched source for a video, waiting for
+// the job to finish if it is still running. Jobs for other videos are
+// cancelled.
+func (v *VoiceInstance) takePrefetched(videoID string) (*preparedSource, bool) {
+	v.mu.Lock()
+	job := v.prefetch
+	if job == nil {
+		v.mu.Unlock()
+		return nil, false
+	}
+	if job.track.PlaybackID != videoID {
+		v.cancelPrefetchLocked()
+		v.mu.Unlock()
+		return nil, false
+	}
+	v.prefetch = nil
+	v.mu.Unlock()
+
+	<-job.done
+	job.cancel()
+
+	if job.err != nil {
+		logger.Log("Prefetch failed, preparing again: "+job.err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Warn,
+		})
+		return nil, false
+	}
+
+	return job.source, true
+}
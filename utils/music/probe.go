@@ -0,0 +1,48 @@
+package music
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ffprobeCommand builds an ffprobe invocation that only reports errors.
+func ffprobeCommand(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "ffprobe", append([]string{"-v", "error"}, args...)...)
+}
+
+// probeDuration asks ffprobe for the length of a media file or URL, giving
+// up after probeTimeout so a stalled server cannot hold up playback.
+func probeDuration(input string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	output, err := ffprobeCommand(ctx, "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", input).Output()
+	if err != nil {
+		return 0, err
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected ffprobe duration %q", strings.TrimSpace(string(output)))
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// FormatDuration renders a duration as mm:ss, or h:mm:ss for long tracks.
+func FormatDuration(d time.Duration) string {
+	totalSeconds := int(d.Round(time.Second) / time.Second)
+	hours := totalSeconds / 3600
+	minutes := (totalSeconds % 3600) / 60
+	seconds := totalSeconds % 60
+
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%02d:%02d", minutes, seconds)
+}
@@ -0,0 +1,203 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"fmt"
+	"math/rand"
+)
+
+// Enqueue appends a track to the guild queue and starts the player if it is
+// idle. It returns the position of the track in the queue, where 0 means the
+// track starts playing right away.
+func (v *VoiceInstance) Enqueue(track *types.Track) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.Queue = append(v.Queue, track)
+
+	if !v.queueRunning {
+		v.queueRunning = true
+		v.stopIdleTimerLocked()
+		go v.playQueue()
+		return 0
+	}
+
+	return len(v.Queue)
+}
+
+// EnqueueMany appends several tracks in order and starts the player if it is
+// idle. It returns the queue position of the first track, where 0 means it
+// starts playing right away.
+func (v *VoiceInstance) EnqueueMany(tracks []*types.Track) int {
+	if len(tracks) == 0 {
+		return -1
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	position := len(v.Queue) + 1
+	v.Queue = append(v.Queue, tracks...)
+
+	if !v.queueRunning {
+		v.queueRunning = true
+		v.stopIdleTimerLocked()
+		go v.playQueue()
+		return 0
+	}
+
+	return position
+}
+
+// Skip stops the current track so the player advances to the next one. It
+// fails while no track is playing, including while the next one is still
+// being resolved.
+func (v *VoiceInstance) Skip() (*types.Track, bool) {
+	v.mu.Lock()
+	current := v.CurrentTrack
+	if current == nil || !v.Playing {
+		v.mu.Unlock()
+		return nil, false
+	}
+	v.skipRequested = true
+	v.mu.Unlock()
+
+	v.Stop()
+	return current, true
+}
+
+// StopPlayback ends the current track and empties the queue while staying
+// connected to the voice channel.
+func (v *VoiceInstance) StopPlayback() {
+	v.ClearQueue()
+
+	v.mu.Lock()
+	v.Loop = types.LoopOff
+	v.mu.Unlock()
+
+	v.Stop()
+}
+
+// Shuffle randomises the order of the upcoming tracks.
+func (v *VoiceInstance) Shuffle() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	rand.Shuffle(len(v.Queue), func(i, j int) {
+		v.Queue[i], v.Queue[j] = v.Queue[j], v.Queue[i]
+	})
+	return len(v.Queue)
+}
+
+// CycleLoop switches to the next loop mode: off, then the current track,
+// then the whole queue.
+func (v *VoiceInstance) CycleLoop() types.LoopMode {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.Loop = (v.Loop + 1) % 3
+	return v.Loop
+}
+
+// nextTrackLocked returns the track that plays after the current one,
+// taking the loop mode into account. The caller must hold v.mu.
+func (v *VoiceInstance) nextTrackLocked() *types.Track {
+	if v.Loop == types.LoopTrack && v.CurrentTrack != nil {
+		return v.CurrentTrack
+	}
+	if len(v.Queue) > 0 {
+		return v.Queue[0]
+	}
+	if v.Loop == types.LoopQueue {
+		return v.CurrentTrack
+	}
+	return nil
+}
+
+// ClearQueue removes every upcoming track without touching the current one.
+func (v *VoiceInstance) ClearQueue() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	removed := len(v.Queue)
+	v.Queue = nil
+	v.cancelPrefetchLocked()
+	v.discardHandoffLocked()
+	return removed
+}
+
+// QueueSnapshot returns the current track and a copy of the upcoming tracks.
+func (v *VoiceInstance) QueueSnapshot() (*types.Track, []*types.Track) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	upcoming := make([]*types.Track, len(v.Queue))
+	copy(upcoming, v.Queue)
+	return v.CurrentTrack, upcoming
+}
+
+func (v *VoiceInstance) playQueue() {
+	for {
+		v.mu.Lock()
+		if len(v.Queue) == 0 {
+			v.CurrentTrack = nil
+			v.queueRunning = false
+			v.startIdleTimerLocked()
+			v.mu.Unlock()
+			return
+		}
+
+		track := v.Queue[0]
+		v.Queue = v.Queue[1:]
+		v.CurrentTrack = track
+		v.TrackDuration = 0
+		v.skipRequested = false
+		v.position.Store(0)
+		v.mu.Unlock()
+
+		logger.Log("Playing next track from queue: "+track.Title, types.LogOptions{
+			Prefix: "Music Queue",
+			Level:  types.Info,
+		})
+
+		if err := v.resolveTrack(track); err != nil {
+			logger.Log("Failed to resolve queued track "+track.Title+": "+err.Error(), types.LogOptions{
+				Prefix: "Music Queue",
+				Level:  types.Error,
+			})
+			v.notify(fmt.Sprintf("⚠️ Skipping **%s**: no playable version was found.", track.Title))
+			continue
+		}
+
+		done := make(chan struct{})
+		go v.announceTrack(track, done)
+
+		err := v.Play(track.PlaybackSource(), track.PlaybackURL, track.PlaybackID)
+		close(done)
+		if err != nil {
+			logger.Log("Failed to play queued track "+track.Title+": "+err.Error(), types.LogOptions{
+				Prefix: "Music Queue",
+				Level:  types.Error,
+			})
+		}
+
+		v.mu.Lock()
+		skipped := v.skipRequested
+		v.skipRequested = false
+
+		switch v.Loop {
+		case types.LoopTrack:
+			if !skipped && err == nil {
+				v.Queue = append([]*types.Track{track}, v.Queue...)
+			}
+		case types.LoopQueue:
+			// Skipped tracks stay in the rotation, but one that failed to
+			// play would otherwise be retried forever without a pause.
+			if err == nil {
+				v.Queue = append(v.Queue, track)
+			}
+		}
+		v.mu.Unlock()
+	}
+}
@@ -0,0 +1,328 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const probeTimeout = 15 * time.Second
+
+var errPrivateAddress = errors.New("links to local or private addresses are not allowed")
+
+// stationClient refuses to dial private addresses, including after redirects.
+var stationClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: probeTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return errPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// checkPublicURL rejects links whose host resolves to a private address.
+func checkPublicURL(ctx context.Context, rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, parsedURL.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// isHLSURL reports whether a link is an HLS playlist, which ffmpeg must fetch itself.
+func isHLSURL(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	return err == nil && strings.HasSuffix(strings.ToLower(parsedURL.Path), ".m3u8")
+}
+
+// IsHTTPURL reports whether the input is a plain http(s) link.
+func IsHTTPURL(input string) bool {
+	return isRemoteInput(input)
+}
+
+// GetHTTPAudioInfo probes a direct audio URL; inputs without a duration are live.
+func GetHTTPAudioInfo(audioURL string) (types.MusicSearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	if err := checkPublicURL(ctx, audioURL); err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	output, err := ffprobeCommand(ctx, "-icy", "1", "-show_entries", "format=duration:format_tags:stream=codec_type",
+		"-of", "json", audioURL).Output()
+	if err != nil {
+		return types.MusicSearchResult{}, fmt.Errorf("ffprobe could not read URL: %w", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+		} `json:"streams"`
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	hasAudio := false
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "audio" {
+			hasAudio = true
+			break
+		}
+	}
+	if !hasAudio {
+		return types.MusicSearchResult{}, fmt.Errorf("no audio stream found")
+	}
+
+	tags := map[string]string{}
+	for key, value := range probe.Format.Tags {
+		tags[strings.ToLower(key)] = strings.TrimSpace(value)
+	}
+
+	result := types.MusicSearchResult{
+		Title:      tags["title"],
+		Artist:     tags["artist"],
+		URL:        audioURL,
+		ID:         audioURL,
+		SourceType: types.HTTP,
+	}
+
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil || seconds <= 0 {
+		result.Live = true
+	} else {
+		result.Duration = FormatDuration(time.Duration(seconds * float64(time.Second)))
+	}
+
+	if station := tags["icy-name"]; station != "" {
+		result.Title, result.Artist = station, ""
+		if streamTitle := tags["streamtitle"]; streamTitle != "" {
+			result.Title, result.Artist = streamTitle, station
+		}
+	}
+
+	if result.Title == "" {
+		result.Title = titleFromURL(audioURL)
+	}
+
+	return result, nil
+}
+
+// titleFromURL names a track after the file in its URL, or its host.
+func titleFromURL(audioURL string) string {
+	parsedURL, err := url.Parse(audioURL)
+	if err != nil {
+		return audioURL
+	}
+
+	name, err := url.PathUnescape(path.Base(parsedURL.Path))
+	if err != nil || name == "/" || name == "." || name == "" {
+		return parsedURL.Host
+	}
+
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// icyReader strips ICY metadata from a station's audio and reports its titles.
+type icyReader struct {
+	body      io.ReadCloser
+	cancel    context.CancelFunc
+	metaInt   int
+	remaining int
+	onTitle   func(string)
+}
+
+func (r *icyReader) Read(p []byte) (int, error) {
+	if r.metaInt == 0 {
+		return r.body.Read(p)
+	}
+
+	if r.remaining == 0 {
+		if err := r.readMetadata(); err != nil {
+			return 0, err
+		}
+		r.remaining = r.metaInt
+	}
+
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.body.Read(p)
+	r.remaining -= n
+	return n, err
+}
+
+// readMetadata reads the block that follows every metaInt bytes of audio.
+func (r *icyReader) readMetadata() error {
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(r.body, length); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+
+	metadata := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(r.body, metadata); err != nil {
+		return err
+	}
+
+	r.onTitle(parseStreamTitle(string(metadata)))
+	return nil
+}
+
+func (r *icyReader) Close() error {
+	r.cancel()
+	return r.body.Close()
+}
+
+// openStationStream connects to a radio stream for the decoder, with ICY titles.
+func (v *VoiceInstance) openStationStream(track *types.Track, streamURL string) (io.ReadCloser, error) {
+	v.mu.Lock()
+	station := track.Artist
+	if station == "" {
+		station = track.Title
+	}
+	v.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", streamURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+
+	// Only connecting is bounded; the stream plays as long as the track.
+	connectTimer := time.AfterFunc(probeTimeout, cancel)
+	resp, err := stationClient.Do(req)
+	connectTimer.Stop()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("station returned %s", resp.Status)
+	}
+
+	metaInt, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err != nil || metaInt <= 0 {
+		return &icyReader{body: resp.Body, cancel: cancel}, nil
+	}
+
+	return &icyReader{
+		body:      resp.Body,
+		cancel:    cancel,
+		metaInt:   metaInt,
+		remaining: metaInt,
+		onTitle: func(streamTitle string) {
+			track = v.setStreamTitle(track, station, streamTitle)
+		},
+	}, nil
+}
+
+// setStreamTitle replaces the current track with a retitled copy and returns it.
+func (v *VoiceInstance) setStreamTitle(track *types.Track, station, streamTitle string) *types.Track {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.CurrentTrack != track {
+		return track
+	}
+
+	updated := *track
+	if streamTitle == "" {
+		updated.Title, updated.Artist = station, ""
+	} else {
+		updated.Title, updated.Artist = streamTitle, station
+	}
+	if updated.Title == track.Title && updated.Artist == track.Artist {
+		return track
+	}
+
+	if streamTitle != "" {
+		logger.Log("Now playing on "+station+": "+streamTitle, types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Debug,
+		})
+	}
+	v.CurrentTrack = &updated
+	return &updated
+}
+
+// parseStreamTitle extracts the title from "StreamTitle='Artist - Song';".
+func parseStreamTitle(metadata string) string {
+	_, rest, found := strings.Cut(metadata, "StreamTitle='")
+	if !found {
+		return ""
+	}
+
+	title, _, _ := strings.Cut(rest, "';")
+	return strings.TrimSpace(strings.TrimRight(title, "\x00"))
+}
+
+// GetAttachmentInfo probes an uploaded file and returns its length.
+func GetAttachmentInfo(attachmentURL, filename string) (types.MusicSearchResult, time.Duration, error) {
+	info, err := GetHTTPAudioInfo(attachmentURL)
+	if err != nil {
+		return types.MusicSearchResult{}, 0, err
+	}
+
+	if info.Live {
+		return types.MusicSearchResult{}, 0, fmt.Errorf("could not read the length of %s", filename)
+	}
+
+	// Untagged files are named after the upload rather than the CDN path.
+	if info.Title == titleFromURL(attachmentURL) {
+		info.Title = strings.TrimSuffix(filename, path.Ext(filename))
+	}
+	info.SourceType = types.Attachment
+
+	return info, parseDisplayDuration(info.Duration), nil
+}
@@ -0,0 +1,140 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"fmt"
+	"time"
+)
+
+const (
+	// voiceSendTimeout is how long a frame may wait on OpusSend before the
+	// voice connection is considered dead.
+	voiceSendTimeout = 5 * time.Second
+
+	// voiceRecoveryWait is how long discordgo's own reconnect of a dropped
+	// voice websocket is waited for before the channel is rejoined from here.
+	voiceRecoveryWait = 45 * time.Second
+	maxRecoveryPoll   = 5 * time.Second
+
+	maxReconnectAttempts = 6
+	maxReconnectBackoff  = 30 * time.Second
+)
+
+// connectionAlive reports whether discordgo still considers the voice
+// connection ready to send audio.
+func (v *VoiceInstance) connectionAlive() bool {
+	v.Connection.RLock()
+	defer v.Connection.RUnlock()
+
+	return v.Connection.Ready
+}
+
+// isReconnecting reports whether playback is waiting for the voice connection
+// to come back. discordgo's own reconnect sends a leave to the gateway when
+// an attempt fails, which must not be mistaken for being disconnected.
+func (v *VoiceInstance) isReconnecting() bool {
+	return v.reconnecting.Load()
+}
+
+// reconnect brings a dropped voice connection back. When the websocket
+// closed, discordgo is already rebuilding the same connection, so that is
+// waited for first. The channel is only rejoined from here when discordgo
+// does not manage it in time or when sends stalled on a connection it still
+// considers ready. It gives up early if the track is stopped.
+func (v *VoiceInstance) reconnect(stopChan chan bool) error {
+	v.reconnecting.Store(true)
+	defer v.reconnecting.Store(false)
+
+	if !v.connectionAlive() {
+		logger.Log("Voice connection lost in guild "+v.GuildID+", waiting for it to reconnect", types.LogOptions{
+			Prefix: "Voice Reconnect",
+			Level:  types.Warn,
+		})
+
+		recovered, err := v.waitForConnection(stopChan)
+		if err != nil {
+			return err
+		}
+		if recovered {
+			v.resumeAfterReconnect()
+			return nil
+		}
+	}
+
+	backoff := time.Second
+
+	for attempt := 1; attempt <= maxReconnectAttempts; attempt++ {
+		logger.Log(fmt.Sprintf("Rejoining voice in guild %s (attempt %d/%d)", v.GuildID, attempt, maxReconnectAttempts), types.LogOptions{
+			Prefix: "Voice Reconnect",
+			Level:  types.Warn,
+		})
+
+		// A connection that is still marked ready has stalled rather than
+		// dropped, so nothing else is going to close it. Close only drops the
+		// websocket and UDP connection; Disconnect would also tell the
+		// gateway we left, which tears the whole instance down.
+		if v.connectionAlive() {
+			v.Connection.Close()
+		}
+
+		vc, err := v.Session.ChannelVoiceJoin(v.GuildID, v.VoiceChannelID(), false, true)
+		if err == nil {
+			v.mu.Lock()
+			v.Connection = vc
+			v.mu.Unlock()
+
+			v.resumeAfterReconnect()
+			return nil
+		}
+
+		logger.Log(fmt.Sprintf("Reconnect attempt %d failed in guild %s: %v", attempt, v.GuildID, err), types.LogOptions{
+			Prefix: "Voice Reconnect",
+			Level:  types.Error,
+		})
+
+		select {
+		case <-time.After(backoff):
+		case <-stopChan:
+			return fmt.Errorf("playback stopped while reconnecting")
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+
+	return fmt.Errorf("could not reconnect to voice in guild %s after %d attempts", v.GuildID, maxReconnectAttempts)
+}
+
+// waitForConnection polls with backoff until discordgo marks the connection
+// ready again, reporting false once voiceRecoveryWait has passed.
+func (v *VoiceInstance) waitForConnection(stopChan chan bool) (bool, error) {
+	deadline := time.Now().Add(voiceRecoveryWait)
+	poll := 250 * time.Millisecond
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-time.After(poll):
+		case <-stopChan:
+			return false, fmt.Errorf("playback stopped while reconnecting")
+		}
+
+		if v.connectionAlive() {
+			return true, nil
+		}
+		poll = min(poll*2, maxRecoveryPoll)
+	}
+
+	logger.Log(fmt.Sprintf("Voice connection in guild %s did not come back within %s", v.GuildID, voiceRecoveryWait), types.LogOptions{
+		Prefix: "Voice Reconnect",
+		Level:  types.Warn,
+	})
+	return false, nil
+}
+
+func (v *VoiceInstance) resumeAfterReconnect() {
+	v.Connection.Speaking(true)
+
+	logger.Log(fmt.Sprintf("Reconnected to voice in guild %s, resuming at %s", v.GuildID, FormatDuration(v.Position())), types.LogOptions{
+		Prefix: "Voice Reconnect",
+		Level:  types.Success,
+	})
+}
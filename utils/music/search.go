@@ -4,14 +4,12 @@ import (
 	"ai/config"
 	"ai/types"
 	"ai/utils/logger"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"regexp"
-	"strings"
-	"sync"
+	"time"
 )
 
 var (
@@ -27,40 +25,97 @@ func IsSpotifyURL(input string) bool {
 	return spotifyRegex.MatchString(input)
 }
 
-func Search(query string, limit int) ([]types.MusicSearchResult, error) {
-	var wg sync.WaitGroup
-	wg.Add(2)
+const (
+	// autocompleteTimeout keeps autocomplete within Discord's deadline.
+	autocompleteTimeout = 2500 * time.Millisecond
+	// SearchTimeout gives yt-dlp backed sources time to answer elsewhere.
+	SearchTimeout = 10 * time.Second
+)
 
-	var youtubeResults []types.MusicSearchResult
-	var spotifyResults []types.MusicSearchResult
-	var youtubeErr, spotifyErr error
+type searchSource struct {
+	sourceType types.SourceType
+	search     func(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, error)
+	// enabled is nil for sources that are always searched.
+	enabled func() bool
+}
 
-	go func() {
-		defer wg.Done()
-		youtubeResults, youtubeErr = SearchYouTube(query, limit/2)
-	}()
+// searchSources are queried concurrently and interleaved in this order.
+var searchSources = []searchSource{
+	{types.YouTube, SearchYouTube, nil},
+	{types.Spotify, SearchSpotify, nil},
+	{types.SoundCloud, SearchSoundCloud, nil},
+	{types.Local, func(_ context.Context, query string, limit int) ([]types.MusicSearchResult, error) {
+		return SearchLocal(query, limit)
+	}, LocalLibrary.Enabled},
+}
 
-	go func() {
-		defer wg.Done()
-		spotifyResults, spotifyErr = SearchSpotify(query, limit/2)
-	}()
+// Search queries every source until ctx is done, cancelling slower ones.
+func Search(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, error) {
+	results, _, err := searchAll(ctx, query, limit)
+	return results, err
+}
 
-	wg.Wait()
+// searchAll is Search that also reports whether every source answered.
+func searchAll(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sources := enabledSearchSources()
+	perSource := (limit + len(sources) - 1) / len(sources)
+
+	type sourceResult struct {
+		index   int
+		results []types.MusicSearchResult
+		err     error
+	}
+
+	resultsChan := make(chan sourceResult, len(sources))
+	for index, source := range sources {
+		go func() {
+			results, err := source.search(ctx, query, perSource)
+			resultsChan <- sourceResult{index, results, err}
+		}()
+	}
+
+	sourceResults := make([][]types.MusicSearchResult, len(sources))
+	var errs []error
+	succeeded := 0
+
+collect:
+	for range sources {
+		select {
+		case result := <-resultsChan:
+			if result.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sources[result.index].sourceType, result.err))
+				continue
+			}
+			sourceResults[result.index] = result.results
+			succeeded++
+		case <-ctx.Done():
+			logger.Log("Search timed out waiting for some sources", types.LogOptions{
+				Prefix: "Search",
+				Level:  types.Warn,
+			})
+			break collect
+		}
+	}
 
-	if youtubeErr != nil && spotifyErr != nil {
-		return nil, fmt.Errorf("both search errors: youtube: %w, spotify: %w", youtubeErr, spotifyErr)
+	if succeeded == 0 && len(errs) > 0 {
+		return nil, false, fmt.Errorf("all searches failed: %w", errors.Join(errs...))
 	}
 
 	results := []types.MusicSearchResult{}
 
-	maxLength := max(len(spotifyResults), len(youtubeResults))
+	maxLength := 0
+	for _, sourceResult := range sourceResults {
+		maxLength = max(maxLength, len(sourceResult))
+	}
 
 	for i := range maxLength {
-		if i < len(youtubeResults) {
-			results = append(results, youtubeResults[i])
-		}
-		if i < len(spotifyResults) {
-			results = append(results, spotifyResults[i])
+		for _, sourceResult := range sourceResults {
+			if i < len(sourceResult) {
+				results = append(results, sourceResult[i])
+			}
 		}
 	}
 
@@ -68,94 +123,56 @@ func Search(query string, limit int) ([]types.MusicSearchResult, error) {
 		results = results[:limit]
 	}
 
-	return results, nil
+	return results, succeeded == len(sources), nil
 }
 
-func SearchSpotify(query string, limit int) ([]types.MusicSearchResult, error) {
-	token, err := getSpotifyToken()
-	if err != nil {
-		logger.Log("Spotify token error: "+err.Error(), types.LogOptions{
-			Prefix: "Search",
-			Level:  types.Error,
-		})
-		return nil, err
+func enabledSearchSources() []searchSource {
+	sources := []searchSource{}
+	for _, source := range searchSources {
+		if source.enabled == nil || source.enabled() {
+			sources = append(sources, source)
+		}
 	}
+	return sources
+}
 
+func SearchSpotify(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, error) {
 	searchURL := fmt.Sprintf("https://api.spotify.com/v1/search?q=%s&type=track&limit=%d", url.QueryEscape(query), limit)
-	req, err := http.NewRequest("GET", searchURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("Authorization", "Bearer "+token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
 
 	var searchResponse types.SpotifySearchResponse
-	if err := json.Unmarshal(body, &searchResponse); err != nil {
+	if err := spotifyGetContext(ctx, searchURL, &searchResponse); err != nil {
+		logger.Log("Spotify search error: "+err.Error(), types.LogOptions{
+			Prefix: "Search",
+			Level:  types.Error,
+		})
 		return nil, err
 	}
 
 	results := []types.MusicSearchResult{}
 
 	for _, item := range searchResponse.Tracks.Items {
-		artistName := ""
-		if len(item.Artists) > 0 {
-			artistName = item.Artists[0].Name
-		}
-
-		thumbnailURL := ""
-		if len(item.Album.Images) > 0 {
-			thumbnailURL = item.Album.Images[0].URL
-		}
-
-		durationSec := item.DurationMs / 1000
-		duration := fmt.Sprintf("%02d:%02d", durationSec/60, durationSec%60)
-
-		results = append(results, types.MusicSearchResult{
-			Title:      item.Name,
-			Artist:     artistName,
-			URL:        item.ExternalUrls.Spotify,
-			ID:         item.ID,
-			Duration:   duration,
-			Thumbnail:  thumbnailURL,
-			SourceType: types.Spotify,
-		})
+		results = append(results, spotifyTrackToResult(item, ""))
 	}
 
 	return results, nil
 }
 
-func SearchYouTube(query string, limit int) ([]types.MusicSearchResult, error) {
-	apiKey := config.Config.YoutubeAPIKey
+// SearchYouTube uses the Data API, or yt-dlp when the API is unavailable.
+func SearchYouTube(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, error) {
+	if !youtubeAPIAvailable() {
+		return searchYouTubeYtdlp(ctx, query, limit)
+	}
+
 	searchURL := fmt.Sprintf(
 		"https://www.googleapis.com/youtube/v3/search?part=snippet&q=%s&key=%s&maxResults=%d&type=video",
-		url.QueryEscape(query), apiKey, limit,
+		url.QueryEscape(query), config.Config.YoutubeAPIKey, limit,
 	)
 
-	resp, err := http.Get(searchURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var searchResponse types.YouTubeSearchResponse
-	if err := json.Unmarshal(body, &searchResponse); err != nil {
+	if err := youtubeGetContext(ctx, searchURL, &searchResponse); err != nil {
+		if shouldUseYtdlp(err) {
+			return searchYouTubeYtdlp(ctx, query, limit)
+		}
 		return nil, err
 	}
 
@@ -172,6 +189,7 @@ func SearchYouTube(query string, limit int) ([]types.MusicSearchResult, error) {
 			Duration:   "00:00",
 			Thumbnail:  item.Snippet.Thumbnails.High.URL,
 			SourceType: types.YouTube,
+			Live:       item.Snippet.LiveBroadcastContent == "live",
 		})
 	}
 
@@ -183,35 +201,32 @@ func GetTrackInfo(id string, sourceType types.SourceType) (types.MusicSearchResu
 		return GetYouTubeInfoByID(id)
 	} else if sourceType == types.Spotify {
 		return GetSpotifyInfoByID(id)
+	} else if sourceType == types.SoundCloud {
+		return GetSoundCloudInfoByID(id)
+	} else if sourceType == types.Local {
+		return GetLocalInfoByID(id)
 	}
 
 	return types.MusicSearchResult{}, fmt.Errorf("unsupported source type: %s", sourceType)
 }
 
 func GetYouTubeInfoByID(videoID string) (types.MusicSearchResult, error) {
-	apiKey := config.Config.YoutubeAPIKey
-	apiURL := fmt.Sprintf(
-		"https://www.googleapis.com/youtube/v3/videos?part=contentDetails,snippet&id=%s&key=%s",
-		videoID, apiKey,
-	)
-
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return types.MusicSearchResult{}, err
+	if !youtubeAPIAvailable() {
+		return getYouTubeInfoYtdlp(videoID)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return types.MusicSearchResult{}, err
-	}
+	apiURL := fmt.Sprintf(
+		"https://www.googleapis.com/youtube/v3/videos?part=contentDetails,snippet,liveStreamingDetails&id=%s&key=%s",
+		url.QueryEscape(videoID), config.Config.YoutubeAPIKey,
+	)
 
 	var response struct {
 		Items []struct {
 			Snippet struct {
-				Title        string `json:"title"`
-				ChannelTitle string `json:"channelTitle"`
-				Thumbnails   struct {
+				Title                string `json:"title"`
+				ChannelTitle         string `json:"channelTitle"`
+				LiveBroadcastContent string `json:"liveBroadcastContent"`
+				Thumbnails           struct {
 					High struct {
 						URL string `json:"url"`
 					} `json:"high"`
@@ -220,11 +235,17 @@ func GetYouTubeInfoByID(videoID string) (types.MusicSearchResult, error) {
 			ContentDetails struct {
 				Duration string `json:"duration"`
 			} `json:"contentDetails"`
+			LiveStreamingDetails struct {
+				ScheduledStartTime string `json:"scheduledStartTime"`
+				ActualEndTime      string `json:"actualEndTime"`
+			} `json:"liveStreamingDetails"`
 		} `json:"items"`
 	}
 
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	if err := youtubeGet(apiURL, &response); err != nil {
+		if shouldUseYtdlp(err) {
+			return getYouTubeInfoYtdlp(videoID)
+		}
 		return types.MusicSearchResult{}, err
 	}
 
@@ -233,119 +254,40 @@ func GetYouTubeInfoByID(videoID string) (types.MusicSearchResult, error) {
 	}
 
 	item := response.Items[0]
+	if err := liveBroadcastError(item.Snippet.LiveBroadcastContent, item.LiveStreamingDetails.ScheduledStartTime,
+		item.LiveStreamingDetails.ActualEndTime, item.ContentDetails.Duration); err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	live := item.Snippet.LiveBroadcastContent == "live"
+	duration := item.ContentDetails.Duration
+	if live {
+		duration = ""
+	}
+
 	return types.MusicSearchResult{
 		Title:      item.Snippet.Title,
 		Artist:     item.Snippet.ChannelTitle,
 		URL:        fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 		ID:         videoID,
-		Duration:   item.ContentDetails.Duration,
+		Duration:   duration,
 		Thumbnail:  item.Snippet.Thumbnails.High.URL,
 		SourceType: types.YouTube,
+		Live:       live,
 	}, nil
 }
 
 func GetSpotifyInfoByID(trackID string) (types.MusicSearchResult, error) {
-	token, err := getSpotifyToken()
-	if err != nil {
+	var track types.SpotifyTrack
+	if err := spotifyGet("https://api.spotify.com/v1/tracks/"+url.PathEscape(trackID), &track); err != nil {
 		return types.MusicSearchResult{}, err
 	}
 
-	apiURL := "https://api.spotify.com/v1/tracks/" + trackID
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return types.MusicSearchResult{}, err
-	}
-
-	req.Header.Add("Authorization", "Bearer "+token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return types.MusicSearchResult{}, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return types.MusicSearchResult{}, err
-	}
-
-	var trackResponse struct {
-		ID      string `json:"id"`
-		Name    string `json:"name"`
-		Artists []struct {
-			Name string `json:"name"`
-		} `json:"artists"`
-		Album struct {
-			Images []struct {
-				URL string `json:"url"`
-			} `json:"images"`
-		} `json:"album"`
-		DurationMs   int `json:"duration_ms"`
-		ExternalUrls struct {
-			Spotify string `json:"spotify"`
-		} `json:"external_urls"`
-	}
-
-	err = json.Unmarshal(body, &trackResponse)
-	if err != nil {
-		return types.MusicSearchResult{}, err
-	}
-
-	artistName := ""
-	if len(trackResponse.Artists) > 0 {
-		artistName = trackResponse.Artists[0].Name
-	}
-
-	thumbnailURL := ""
-	if len(trackResponse.Album.Images) > 0 {
-		thumbnailURL = trackResponse.Album.Images[0].URL
-	}
-
-	duration := fmt.Sprintf("%02d:%02d", trackResponse.DurationMs/60000, (trackResponse.DurationMs/1000)%60)
-
-	return types.MusicSearchResult{
-		Title:      trackResponse.Name,
-		Artist:     artistName,
-		URL:        trackResponse.ExternalUrls.Spotify,
-		ID:         trackResponse.ID,
-		Duration:   duration,
-		Thumbnail:  thumbnailURL,
-		SourceType: types.Spotify,
-	}, nil
-}
-
-func GetYouTubeForSpotify(title, artist string) (types.MusicSearchResult, error) {
-	query := fmt.Sprintf("%s %s", title, artist)
-
-	results, err := SearchYouTube(query, 1)
-	if err != nil {
-		return types.MusicSearchResult{}, err
-	}
-
-	if len(results) == 0 {
-		return types.MusicSearchResult{}, fmt.Errorf("no YouTube results found")
-	}
-
-	return results[0], nil
+	return spotifyTrackToResult(track, ""), nil
 }
 
 func GetYouTubeInfo(ytURL string) (types.MusicSearchResult, error) {
-	var videoID string
-
-	if strings.Contains(ytURL, "youtu.be") {
-		parts := strings.Split(ytURL, "/")
-		videoID = parts[len(parts)-1]
-	} else if strings.Contains(ytURL, "youtube.com") {
-		parsedURL, err := url.Parse(ytURL)
-		if err != nil {
-			return types.MusicSearchResult{}, err
-		}
-
-		query := parsedURL.Query()
-		videoID = query.Get("v")
-	}
+	videoID, _ := ParseYouTubeURL(ytURL)
 
 	if videoID == "" {
 		return types.MusicSearchResult{}, fmt.Errorf("could not extract video ID from URL")
@@ -355,67 +297,14 @@ func GetYouTubeInfo(ytURL string) (types.MusicSearchResult, error) {
 }
 
 func GetSpotifyInfo(spotifyURL string) (types.MusicSearchResult, error) {
-	var trackID string
-
-	if strings.Contains(spotifyURL, "track") {
-		parts := strings.Split(spotifyURL, "/")
-		trackID = parts[len(parts)-1]
-
-		if strings.Contains(trackID, "?") {
-			trackID = strings.Split(trackID, "?")[0]
-		}
-	} else {
-		return types.MusicSearchResult{}, fmt.Errorf("URL must be a Spotify track URL")
-	}
-
-	if trackID == "" {
-		return types.MusicSearchResult{}, fmt.Errorf("could not extract track ID from URL")
-	}
-
-	return GetSpotifyInfoByID(trackID)
-}
-
-func getSpotifyToken() (string, error) {
-	clientID := config.Config.SpotifyClientId
-	clientSecret := config.Config.SpotifyClientSecret
-
-	tokenURL := "https://accounts.spotify.com/api/token"
-
-	data := url.Values{}
-	data.Set("grant_type", "client_credentials")
-
-	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(clientID, clientSecret)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	kind, trackID, err := ParseSpotifyURL(spotifyURL)
 	if err != nil {
-		return "", err
-	}
-
-	var tokenResponse struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
-		ExpiresIn   int    `json:"expires_in"`
+		return types.MusicSearchResult{}, err
 	}
 
-	if err := json.Unmarshal(body, &tokenResponse); err != nil {
-		return "", err
-	}
-	if tokenResponse.TokenType != "Bearer" {
-		return "", fmt.Errorf("unexpected token type: %s", tokenResponse.TokenType)
+	if kind != "track" {
+		return types.MusicSearchResult{}, fmt.Errorf("URL must be a Spotify track URL")
 	}
 
-	return tokenResponse.AccessToken, nil
+	return GetSpotifyInfoByID(trackID)
 }
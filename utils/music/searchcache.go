@@ -0,0 +1,294 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// searchCacheMinPrefix is the shortest cached query whose results are
+	// reused for longer queries that start with it.
+	searchCacheMinPrefix = 3
+	// searchResultOverhead roughly covers a result's fixed-size fields and
+	// the bookkeeping around each entry when estimating memory use.
+	searchResultOverhead = 160
+	// searchCachePartialTTL is how long results are kept when some source
+	// failed or timed out, so its results show up again soon.
+	searchCachePartialTTL = 30 * time.Second
+)
+
+type searchCacheEntry struct {
+	query   string
+	limit   int
+	results []types.MusicSearchResult
+	expires time.Time
+	size    int
+}
+
+// searchCache keeps recent search results keyed by their normalised query,
+// so autocomplete does not search every source again on each keystroke.
+// Entries expire after a TTL and the least recently used ones are evicted
+// once their estimated size passes the memory budget.
+type searchCache struct {
+	mu       sync.Mutex
+	maxSize  int
+	ttl      time.Duration
+	size     int
+	order    *list.List
+	entries  map[string]*list.Element
+	inFlight map[string]bool
+}
+
+var SearchCache = &searchCache{
+	order:    list.New(),
+	entries:  make(map[string]*list.Element),
+	inFlight: make(map[string]bool),
+}
+
+// LoadSearchCache applies the configured memory budget and TTL.
+func LoadSearchCache() {
+	c := SearchCache
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = config.Config.SearchCacheMaxKB * 1024
+	c.ttl = time.Duration(config.Config.SearchCacheTTL) * time.Second
+
+	if c.maxSize <= 0 || c.ttl <= 0 {
+		logger.Log("Search cache disabled", types.LogOptions{
+			Prefix: "Search",
+			Level:  types.Info,
+		})
+	}
+}
+
+func (c *searchCache) enabled() bool {
+	return c.maxSize > 0 && c.ttl > 0
+}
+
+// normalizeQuery lowercases a query and collapses its whitespace, so
+// queries differing only in case or spacing share an entry.
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// CachedSearch is Search with results cached per query. When only a shorter
+// query typed earlier is cached, its results that still match are returned
+// straight away while the full query is searched in the background for the
+// next keystroke. Results missing a source are searched again the same way.
+func CachedSearch(query string, limit int) ([]types.MusicSearchResult, error) {
+	c := SearchCache
+	key := normalizeQuery(query)
+
+	ctx, cancel := context.WithTimeout(context.Background(), autocompleteTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	if !c.enabled() {
+		c.mu.Unlock()
+		return Search(ctx, query, limit)
+	}
+
+	if results, ok := c.get(key, limit); ok {
+		c.mu.Unlock()
+		return results, nil
+	}
+
+	results, ok := c.fromPrefix(key, limit)
+	c.mu.Unlock()
+
+	if ok {
+		c.refresh(query, key, limit)
+		return results, nil
+	}
+
+	results, complete, err := searchAll(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(key, limit, results, complete)
+	if !complete {
+		c.refresh(query, key, limit)
+	}
+	return results, nil
+}
+
+// get returns the cached results for key, unless they have expired or were
+// searched with a smaller limit. The caller must hold c.mu.
+func (c *searchCache) get(key string, limit int) ([]types.MusicSearchResult, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*searchCacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	if entry.limit < limit {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return truncateResults(entry.results, limit), true
+}
+
+// fromPrefix looks for the longest cached query that key extends and keeps
+// the results that still match every word of key, treating the last word as
+// a prefix since it may still be being typed. The caller must hold c.mu.
+func (c *searchCache) fromPrefix(key string, limit int) ([]types.MusicSearchResult, bool) {
+	words := strings.Fields(normalizeWords(key))
+	if len(words) == 0 {
+		return nil, false
+	}
+
+	for end := len(key) - 1; end >= searchCacheMinPrefix; end-- {
+		prefix := strings.TrimSpace(key[:end])
+		if len(prefix) < searchCacheMinPrefix {
+			break
+		}
+
+		cached, ok := c.get(prefix, limit)
+		if !ok {
+			continue
+		}
+
+		results := []types.MusicSearchResult{}
+		for _, result := range cached {
+			if resultMatches(result, words) {
+				results = append(results, result)
+			}
+		}
+
+		if len(results) > 0 {
+			return results, true
+		}
+		return nil, false
+	}
+
+	return nil, false
+}
+
+// resultMatches reports whether every word of the query appears in the
+// result's title or artist. The last word only has to start a word, since
+// it may still be being typed.
+func resultMatches(result types.MusicSearchResult, words []string) bool {
+	fields := strings.Fields(normalizeWords(result.Title + " " + result.Artist))
+
+	for index, word := range words {
+		found := false
+		for _, field := range fields {
+			if field == word || index == len(words)-1 && strings.HasPrefix(field, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// refresh searches the full query in the background and caches the results,
+// unless that query is already being searched.
+func (c *searchCache) refresh(query, key string, limit int) {
+	c.mu.Lock()
+	if c.inFlight[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.inFlight[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.inFlight, key)
+			c.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), SearchTimeout)
+		defer cancel()
+
+		results, complete, err := searchAll(ctx, query, limit)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Background search for %q failed: %v", query, err), types.LogOptions{
+				Prefix: "Search",
+				Level:  types.Debug,
+			})
+			return
+		}
+
+		c.put(key, limit, results, complete)
+	}()
+}
+
+// put stores results under key and evicts the least recently used entries
+// until the cache fits its memory budget again. Incomplete results expire
+// sooner.
+func (c *searchCache) put(key string, limit int, results []types.MusicSearchResult, complete bool) {
+	entry := &searchCacheEntry{
+		query:   key,
+		limit:   limit,
+		results: results,
+		size:    estimateResultsSize(key, results),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.size > c.maxSize {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	ttl := c.ttl
+	if !complete {
+		ttl = min(ttl, searchCachePartialTTL)
+	}
+	entry.expires = time.Now().Add(ttl)
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += entry.size
+
+	for c.size > c.maxSize {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry. The caller must hold c.mu.
+func (c *searchCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*searchCacheEntry)
+	delete(c.entries, entry.query)
+	c.size -= entry.size
+}
+
+func estimateResultsSize(key string, results []types.MusicSearchResult) int {
+	size := len(key) + searchResultOverhead
+	for _, result := range results {
+		size += searchResultOverhead + len(result.Title) + len(result.Artist) + len(result.URL) +
+			len(result.ID) + len(result.Duration) + len(result.Thumbnail)
+	}
+	return size
+}
+
+func truncateResults(results []types.MusicSearchResult, limit int) []types.MusicSearchResult {
+	if len(results) > limit {
+		return results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,203 @@
+package music
+
+import (
+	"ai/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// soundcloudResolveBatch is how many set entries are looked up per yt-dlp
+	// run, which is also the page size used when queueing a set.
+	soundcloudResolveBatch = 10
+	soundcloudTimeout      = time.Minute
+)
+
+var soundcloudRegex = regexp.MustCompile(`^(https?://)?((www|m|on)\.)?soundcloud\.com/.+`)
+
+// SoundCloudPlaylist is a SoundCloud set, album or profile. yt-dlp only
+// lists the track URLs of a set, so their metadata is looked up in batches
+// while the set is queued.
+type SoundCloudPlaylist struct {
+	Name    string
+	entries []string
+}
+
+type soundcloudInfo struct {
+	Type       string  `json:"_type"`
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Uploader   string  `json:"uploader"`
+	Duration   float64 `json:"duration"`
+	Thumbnail  string  `json:"thumbnail"`
+	WebpageURL string  `json:"webpage_url"`
+	URL        string  `json:"url"`
+	Thumbnails []struct {
+		URL string `json:"url"`
+	} `json:"thumbnails"`
+	Entries []soundcloudInfo `json:"entries"`
+}
+
+func IsSoundCloudURL(input string) bool {
+	return soundcloudRegex.MatchString(input)
+}
+
+func SearchSoundCloud(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, error) {
+	output, err := ytdlpCommand(ctx, "--flat-playlist", "--dump-single-json", "--",
+		fmt.Sprintf("scsearch%d:%s", limit, query)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp SoundCloud search failed: %w", err)
+	}
+
+	var response soundcloudInfo
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, err
+	}
+
+	results := []types.MusicSearchResult{}
+	for _, entry := range response.Entries {
+		if entry.ID == "" {
+			continue
+		}
+		results = append(results, entry.result())
+	}
+
+	return results, nil
+}
+
+func GetSoundCloudInfoByID(trackID string) (types.MusicSearchResult, error) {
+	return GetSoundCloudInfo("https://api.soundcloud.com/tracks/" + trackID)
+}
+
+func GetSoundCloudInfo(scURL string) (types.MusicSearchResult, error) {
+	result, playlist, err := GetSoundCloudURL(scURL)
+	if err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	if playlist != nil {
+		return types.MusicSearchResult{}, fmt.Errorf("URL must be a SoundCloud track URL")
+	}
+
+	return result, nil
+}
+
+// GetSoundCloudURL resolves a SoundCloud link. Tracks come back as a single
+// result; sets, albums and profiles as a playlist whose tracks are looked up
+// while they are queued.
+func GetSoundCloudURL(scURL string) (types.MusicSearchResult, *SoundCloudPlaylist, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), soundcloudTimeout)
+	defer cancel()
+
+	output, err := ytdlpCommand(ctx, "--flat-playlist", "--dump-single-json", "--", scURL).Output()
+	if err != nil {
+		return types.MusicSearchResult{}, nil, fmt.Errorf("yt-dlp could not resolve SoundCloud URL: %w", err)
+	}
+
+	var info soundcloudInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return types.MusicSearchResult{}, nil, err
+	}
+
+	if info.Type != "playlist" {
+		if info.ID == "" {
+			return types.MusicSearchResult{}, nil, fmt.Errorf("SoundCloud track not found")
+		}
+		return info.result(), nil, nil
+	}
+
+	playlist := &SoundCloudPlaylist{Name: info.Title, entries: []string{}}
+	for _, entry := range info.Entries {
+		entryURL := entry.WebpageURL
+		if entryURL == "" {
+			entryURL = entry.URL
+		}
+		if entryURL != "" {
+			playlist.entries = append(playlist.entries, entryURL)
+		}
+	}
+
+	if playlist.Name == "" {
+		playlist.Name = "SoundCloud playlist"
+	}
+
+	return types.MusicSearchResult{}, playlist, nil
+}
+
+// FetchTracks looks up the set's tracks in batches, calling onPage with each
+// batch until the set is exhausted, limit tracks have been delivered or
+// onPage returns false. Tracks that cannot be resolved are left out.
+func (p *SoundCloudPlaylist) FetchTracks(limit int, onPage func([]types.MusicSearchResult) bool) error {
+	deliver := limitPages(limit, onPage)
+
+	entries := p.entries
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	for start := 0; start < len(entries); start += soundcloudResolveBatch {
+		end := min(start+soundcloudResolveBatch, len(entries))
+
+		tracks, err := resolveSoundCloudBatch(entries[start:end])
+		if err != nil {
+			return err
+		}
+
+		if !deliver(tracks) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// resolveSoundCloudBatch looks up several tracks with a single yt-dlp run,
+// which prints one JSON document per track it could resolve.
+func resolveSoundCloudBatch(urls []string) ([]types.MusicSearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), soundcloudTimeout)
+	defer cancel()
+
+	args := append([]string{"--ignore-errors", "--no-playlist", "--dump-json", "--"}, urls...)
+	// yt-dlp exits non-zero when any track fails, so only the output matters.
+	output, _ := ytdlpCommand(ctx, args...).Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out resolving SoundCloud tracks")
+	}
+
+	tracks := []types.MusicSearchResult{}
+	for _, line := range strings.Split(string(output), "\n") {
+		var info soundcloudInfo
+		if line == "" || json.Unmarshal([]byte(line), &info) != nil || info.ID == "" {
+			continue
+		}
+		tracks = append(tracks, info.result())
+	}
+
+	return tracks, nil
+}
+
+func (info soundcloudInfo) result() types.MusicSearchResult {
+	trackURL := info.WebpageURL
+	if trackURL == "" {
+		trackURL = info.URL
+	}
+
+	thumbnail := info.Thumbnail
+	if thumbnail == "" && len(info.Thumbnails) > 0 {
+		thumbnail = info.Thumbnails[len(info.Thumbnails)-1].URL
+	}
+
+	return types.MusicSearchResult{
+		Title:      info.Title,
+		Artist:     info.Uploader,
+		URL:        trackURL,
+		ID:         info.ID,
+		Duration:   FormatDuration(time.Duration(info.Duration * float64(time.Second))),
+		Thumbnail:  thumbnail,
+		SourceType: types.SoundCloud,
+	}
+}
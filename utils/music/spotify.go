@@ -0,0 +1,400 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spotifyPlaylistPageSize = 100
+	spotifyAlbumPageSize    = 50
+)
+
+// SpotifyCollection describes a Spotify playlist, album or artist whose
+// tracks are fetched page by page.
+type SpotifyCollection struct {
+	Kind string
+	ID   string
+	Name string
+}
+
+// ParseSpotifyURL extracts the resource kind (track, playlist, album or
+// artist) and ID from an open.spotify.com URL.
+func ParseSpotifyURL(spotifyURL string) (string, string, error) {
+	if !strings.Contains(spotifyURL, "://") {
+		spotifyURL = "https://" + spotifyURL
+	}
+
+	parsedURL, err := url.Parse(spotifyURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	// Localised links look like /intl-de/track/<id>.
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "intl-") {
+		parts = parts[1:]
+	}
+
+	if len(parts) < 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("could not extract an ID from the Spotify URL")
+	}
+
+	switch parts[0] {
+	case "track", "playlist", "album", "artist":
+		return parts[0], parts[1], nil
+	}
+
+	return "", "", fmt.Errorf("unsupported Spotify URL type: %s", parts[0])
+}
+
+// IsSpotifyCollectionURL reports whether the URL points at a playlist, album
+// or artist rather than a single track.
+func IsSpotifyCollectionURL(input string) bool {
+	kind, _, err := ParseSpotifyURL(input)
+	return err == nil && kind != "track"
+}
+
+// GetSpotifyCollection looks up the name of a playlist, album or artist.
+func GetSpotifyCollection(spotifyURL string) (SpotifyCollection, error) {
+	kind, id, err := ParseSpotifyURL(spotifyURL)
+	if err != nil {
+		return SpotifyCollection{}, err
+	}
+
+	collection := SpotifyCollection{Kind: kind, ID: id}
+	escapedID := url.PathEscape(id)
+
+	switch kind {
+	case "playlist":
+		var playlist types.SpotifyPlaylistResponse
+		err = spotifyGet("https://api.spotify.com/v1/playlists/"+escapedID+"?fields=name", &playlist)
+		collection.Name = playlist.Name
+	case "album":
+		var album types.SpotifyAlbumResponse
+		err = spotifyGet("https://api.spotify.com/v1/albums/"+escapedID, &album)
+		collection.Name = album.Name
+	case "artist":
+		var artist types.SpotifyArtistResponse
+		err = spotifyGet("https://api.spotify.com/v1/artists/"+escapedID, &artist)
+		collection.Name = artist.Name
+	default:
+		return SpotifyCollection{}, fmt.Errorf("URL is a single track, not a collection")
+	}
+
+	if err != nil {
+		return SpotifyCollection{}, err
+	}
+	return collection, nil
+}
+
+// FetchTracks walks the collection page by page, calling onPage with each
+// batch of tracks until the collection is exhausted, limit tracks have been
+// delivered or onPage returns false. Albums and playlists are paginated past
+// the API page size; artists return their top tracks.
+func (c SpotifyCollection) FetchTracks(limit int, onPage func([]types.MusicSearchResult) bool) error {
+	deliver := limitPages(limit, onPage)
+	escapedID := url.PathEscape(c.ID)
+
+	switch c.Kind {
+	case "playlist":
+		nextURL := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?limit=%d", escapedID, spotifyPlaylistPageSize)
+		for nextURL != "" {
+			var page types.SpotifyPlaylistTracksResponse
+			if err := spotifyGet(nextURL, &page); err != nil {
+				return err
+			}
+
+			tracks := []types.MusicSearchResult{}
+			for _, item := range page.Items {
+				// Podcast episodes, removed tracks and local files cannot be
+				// matched on YouTube.
+				if item.Track == nil || item.Track.IsLocal || item.Track.ID == "" || (item.Track.Type != "" && item.Track.Type != "track") {
+					continue
+				}
+				tracks = append(tracks, spotifyTrackToResult(*item.Track, ""))
+			}
+
+			if !deliver(tracks) {
+				return nil
+			}
+			nextURL = page.Next
+		}
+
+	case "album":
+		var album types.SpotifyAlbumResponse
+		if err := spotifyGet("https://api.spotify.com/v1/albums/"+escapedID, &album); err != nil {
+			return err
+		}
+		thumbnail := ""
+		if len(album.Images) > 0 {
+			thumbnail = album.Images[0].URL
+		}
+
+		nextURL := fmt.Sprintf("https://api.spotify.com/v1/albums/%s/tracks?limit=%d", escapedID, spotifyAlbumPageSize)
+		for nextURL != "" {
+			var page types.SpotifyAlbumTracksResponse
+			if err := spotifyGet(nextURL, &page); err != nil {
+				return err
+			}
+
+			tracks := []types.MusicSearchResult{}
+			for _, item := range page.Items {
+				tracks = append(tracks, spotifyTrackToResult(item, thumbnail))
+			}
+
+			if !deliver(tracks) {
+				return nil
+			}
+			nextURL = page.Next
+		}
+
+	case "artist":
+		var topTracks types.SpotifyArtistTopTracksResponse
+		if err := spotifyGet("https://api.spotify.com/v1/artists/"+escapedID+"/top-tracks?market=US", &topTracks); err != nil {
+			return err
+		}
+
+		tracks := []types.MusicSearchResult{}
+		for _, item := range topTracks.Tracks {
+			tracks = append(tracks, spotifyTrackToResult(item, ""))
+		}
+		deliver(tracks)
+
+	default:
+		return fmt.Errorf("unsupported Spotify collection type: %s", c.Kind)
+	}
+
+	return nil
+}
+
+func spotifyTrackToResult(track types.SpotifyTrack, fallbackThumbnail string) types.MusicSearchResult {
+	artistName := ""
+	if len(track.Artists) > 0 {
+		artistName = track.Artists[0].Name
+	}
+
+	thumbnailURL := fallbackThumbnail
+	if len(track.Album.Images) > 0 {
+		thumbnailURL = track.Album.Images[0].URL
+	}
+
+	durationSec := track.DurationMs / 1000
+
+	return types.MusicSearchResult{
+		Title:      track.Name,
+		Artist:     artistName,
+		URL:        track.ExternalUrls.Spotify,
+		ID:         track.ID,
+		Duration:   fmt.Sprintf("%02d:%02d", durationSec/60, durationSec%60),
+		Thumbnail:  thumbnailURL,
+		SourceType: types.Spotify,
+	}
+}
+
+// spotifyTokenMargin is how long before its expiry a token is refreshed, so
+// requests never go out with a token that expires on the way.
+const spotifyTokenMargin = time.Minute
+
+// spotifyTokenProvider caches the client-credentials access token. Callers
+// that find it missing or about to expire share a single refresh.
+type spotifyTokenProvider struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	refresh   *spotifyTokenRefresh
+}
+
+type spotifyTokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+var spotifyToken = &spotifyTokenProvider{}
+
+// Token returns a valid access token, requesting a new one when needed.
+func (p *spotifyTokenProvider) Token() (string, error) {
+	p.mu.Lock()
+	if p.token != "" && time.Now().Before(p.expiresAt.Add(-spotifyTokenMargin)) {
+		token := p.token
+		p.mu.Unlock()
+		return token, nil
+	}
+
+	if refresh := p.refresh; refresh != nil {
+		p.mu.Unlock()
+		<-refresh.done
+		return refresh.token, refresh.err
+	}
+
+	refresh := &spotifyTokenRefresh{done: make(chan struct{})}
+	p.refresh = refresh
+	p.mu.Unlock()
+
+	token, expiresIn, err := requestSpotifyToken()
+
+	p.mu.Lock()
+	if err == nil {
+		p.token = token
+		p.expiresAt = time.Now().Add(expiresIn)
+	}
+	p.refresh = nil
+	p.mu.Unlock()
+
+	refresh.token, refresh.err = token, err
+	close(refresh.done)
+
+	return token, err
+}
+
+// Invalidate drops the cached token after the API rejected it. Tokens that
+// have already been replaced are left alone, so a burst of rejected requests
+// only causes one refresh.
+func (p *spotifyTokenProvider) Invalidate(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+func requestSpotifyToken() (string, time.Duration, error) {
+	clientID := config.Config.SpotifyClientId
+	clientSecret := config.Config.SpotifyClientSecret
+
+	tokenURL := "https://accounts.spotify.com/api/token"
+
+	data := url.Values{}
+	data.Set("grant_type", "client_credentials")
+
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("spotify token request returned %s", resp.Status)
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", 0, err
+	}
+	if tokenResponse.TokenType != "Bearer" {
+		return "", 0, fmt.Errorf("unexpected token type: %s", tokenResponse.TokenType)
+	}
+
+	logger.Log(fmt.Sprintf("Refreshed Spotify access token, valid for %ds", tokenResponse.ExpiresIn), types.LogOptions{
+		Prefix: "Search",
+		Level:  types.Debug,
+	})
+
+	return tokenResponse.AccessToken, time.Duration(tokenResponse.ExpiresIn) * time.Second, nil
+}
+
+// spotifyGet performs an authenticated Web API request and decodes the JSON
+// response into out. A request rejected with 401 is retried once with a
+// fresh token.
+func spotifyGet(apiURL string, out interface{}) error {
+	return spotifyGetContext(context.Background(), apiURL, out)
+}
+
+func spotifyGetContext(ctx context.Context, apiURL string, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := spotifyToken.Token()
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Add("Authorization", "Bearer "+token)
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			spotifyToken.Invalidate(token)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("spotify API returned %s", resp.Status)
+		}
+
+		return json.Unmarshal(body, out)
+	}
+}
+
+// resolveTrack finds the YouTube video that plays a lazily queued track,
+// such as an item from a Spotify playlist.
+func (v *VoiceInstance) resolveTrack(track *types.Track) error {
+	v.mu.Lock()
+	// Local tracks are played by their library ID and have no URL.
+	resolved := track.PlaybackURL != "" || track.SourceType == types.Local
+	v.mu.Unlock()
+
+	if resolved {
+		return nil
+	}
+
+	if track.SourceType != types.Spotify {
+		return fmt.Errorf("track %s has no playback URL", track.Title)
+	}
+
+	ytTrack, err := GetYouTubeForSpotify(track.MusicSearchResult)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	track.PlaybackURL = ytTrack.URL
+	track.PlaybackID = ytTrack.ID
+	v.mu.Unlock()
+
+	return nil
+}
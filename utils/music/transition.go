@@ -0,0 +1,173 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"time"
+)
+
+const (
+	maxCrossfade = 12 * time.Second
+
+	// gaplessLead is how early the next decoder is started in gapless mode so
+	// it is already producing frames when the current track ends.
+	gaplessLead = time.Second
+
+	// trailingSilenceWindow is the part of a track, counted from its end, in
+	// which a run of silence ends the track early in gapless mode.
+	trailingSilenceWindow = 8 * time.Second
+	trailingSilenceRun    = 500 * time.Millisecond
+
+	// maxLeadingSilence caps how much silence is skipped at the start of a
+	// track in gapless mode.
+	maxLeadingSilence = 5 * time.Second
+)
+
+// trackHandoff is the next track, already decoding, that the player
+// continues with once the current track ends instead of starting from
+// scratch.
+type trackHandoff struct {
+	track    *types.Track
+	source   *preparedSource
+	decoder  *decoder
+	position time.Duration
+}
+
+// SetCrossfade sets how long consecutive tracks overlap. Zero disables the
+// crossfade.
+func (v *VoiceInstance) SetCrossfade(length time.Duration) time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.Crossfade = min(max(length, 0), maxCrossfade)
+	return v.Crossfade
+}
+
+// CrossfadeLength returns how long consecutive tracks overlap.
+func (v *VoiceInstance) CrossfadeLength() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.Crossfade
+}
+
+// SetGapless toggles gapless transitions, which start the next track without
+// a pause and trim silence at the edges of tracks.
+func (v *VoiceInstance) SetGapless(enabled bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.Gapless = enabled
+}
+
+// transitionSettings returns how long before the end of a track the next one
+// should start decoding, the fade length and whether gapless mode is on.
+func (v *VoiceInstance) transitionSettings() (time.Duration, time.Duration, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	lead := v.Crossfade
+	if v.Gapless {
+		lead = max(lead, gaplessLead)
+	}
+	return lead, v.Crossfade, v.Gapless
+}
+
+// beginTransition starts decoding the prefetched next track so it can be
+// mixed into the tail of the current one. It returns nil when the next track
+// is not ready yet.
+func (v *VoiceInstance) beginTransition() *trackHandoff {
+	v.mu.Lock()
+	job := v.prefetch
+	if job == nil || v.nextTrackLocked() != job.track {
+		v.mu.Unlock()
+		return nil
+	}
+
+	select {
+	case <-job.done:
+	default:
+		v.mu.Unlock()
+		return nil
+	}
+
+	if job.err != nil {
+		v.mu.Unlock()
+		return nil
+	}
+	v.prefetch = nil
+	v.mu.Unlock()
+
+	dec, err := v.startDecoder(job.source.input, 0)
+	if err != nil {
+		job.source.release()
+		return nil
+	}
+
+	handoff := &trackHandoff{
+		track:   job.track,
+		source:  job.source,
+		decoder: dec,
+	}
+
+	v.mu.Lock()
+	v.discardHandoffLocked()
+	v.handoff = handoff
+	v.mu.Unlock()
+
+	logger.Log("Starting transition into: "+job.track.Title, types.LogOptions{
+		Prefix: "Music Player",
+		Level:  types.Debug,
+	})
+
+	return handoff
+}
+
+func (v *VoiceInstance) currentHandoff() *trackHandoff {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.handoff
+}
+
+// takeHandoff returns the already-decoding next track if it matches the
+// video about to play, discarding it otherwise.
+func (v *VoiceInstance) takeHandoff(videoID string) *trackHandoff {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	handoff := v.handoff
+	if handoff == nil {
+		return nil
+	}
+
+	if handoff.track.PlaybackID != videoID {
+		v.discardHandoffLocked()
+		return nil
+	}
+
+	v.handoff = nil
+	return handoff
+}
+
+// discardHandoff drops the given handoff if it is still the pending one.
+func (v *VoiceInstance) discardHandoff(handoff *trackHandoff) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.handoff == handoff {
+		v.discardHandoffLocked()
+	}
+}
+
+// discardHandoffLocked stops the pending next-track decoder and releases its
+// source. The caller must hold v.mu.
+func (v *VoiceInstance) discardHandoffLocked() {
+	if v.handoff == nil {
+		return
+	}
+
+	v.handoff.decoder.close()
+	v.handoff.source.release()
+	v.handoff = nil
+}
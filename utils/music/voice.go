@@ -1,14 +1,16 @@
 package music
 
 import (
+	"ai/config"
 	"ai/types"
 	"ai/utils/logger"
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -20,6 +22,10 @@ const (
 	frameRate int = 48000
 	frameSize int = 960
 	maxBytes  int = (frameSize * 2) * 2
+
+	maxVolume int = 200
+
+	frameDuration = time.Duration(frameSize) * time.Second / time.Duration(frameRate)
 )
 
 type VoiceInstance struct {
@@ -27,10 +33,39 @@ type VoiceInstance struct {
 	ChannelID      string
 	Connection     *discordgo.VoiceConnection
 	Playing        bool
+	Paused         bool
 	StopChannel    chan bool
+	resumeChannel  chan struct{}
 	OpusEncoder    *gopus.Encoder
 	mu             sync.Mutex
 	CurrentTrackID string
+	CurrentTrack   *types.Track
+	Queue          []*types.Track
+	queueRunning   bool
+	TrackDuration  time.Duration
+	position       atomic.Int64
+	seekPending    bool
+	seekTarget     time.Duration
+	seekSignal     chan struct{}
+	Volume         int
+	gain           float64
+	Filters        []string
+	Equalizer      []EqualizerBand
+	prefetch       *prefetchJob
+	Crossfade      time.Duration
+	Gapless        bool
+	handoff        *trackHandoff
+	Session        *discordgo.Session
+	TextChannelID  string
+	nowPlaying     *nowPlayingMessage
+	Loop           types.LoopMode
+	skipRequested  bool
+	StayConnected  bool
+	idleTimer      *time.Timer
+	emptyTimer     *time.Timer
+	ServerMuted    bool
+	pausedByMute   bool
+	reconnecting   atomic.Bool
 }
 
 var (
@@ -38,6 +73,9 @@ var (
 	VoiceMutex      = &sync.Mutex{}
 )
 
+// ErrNotSeekable is returned by Seek while a live stream is playing.
+var ErrNotSeekable = errors.New("live streams cannot be seeked")
+
 func (v *VoiceInstance) Stop() {
 	v.mu.Lock()
 	defer v.mu.Unlock()
@@ -51,6 +89,187 @@ func (v *VoiceInstance) Stop() {
 		v.StopChannel = make(chan bool, 1)
 		v.Playing = false
 	}
+
+	if v.Paused {
+		v.Paused = false
+		close(v.resumeChannel)
+	}
+}
+
+// Pause freezes the frame loop of the current track. The ffmpeg process is
+// kept alive and blocks on its output pipe until playback is resumed.
+func (v *VoiceInstance) Pause() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.Playing || v.Paused {
+		return false
+	}
+
+	v.Paused = true
+	v.resumeChannel = make(chan struct{})
+	return true
+}
+
+// Resume continues a paused track from the frame it stopped at.
+func (v *VoiceInstance) Resume() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.Paused {
+		return false
+	}
+
+	v.Paused = false
+	close(v.resumeChannel)
+	return true
+}
+
+// IsPaused reports whether playback is paused.
+func (v *VoiceInstance) IsPaused() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.Paused
+}
+
+// waitIfPaused blocks while playback is paused. It returns false if the
+// track was stopped while waiting.
+func (v *VoiceInstance) waitIfPaused(stopChan chan bool) bool {
+	v.mu.Lock()
+	paused, resumeChan := v.Paused, v.resumeChannel
+	v.mu.Unlock()
+
+	if !paused {
+		return true
+	}
+
+	v.Connection.Speaking(false)
+
+	select {
+	case <-resumeChan:
+		v.Connection.Speaking(true)
+		return true
+	case <-v.seekSignal:
+		return true
+	case <-stopChan:
+		return false
+	}
+}
+
+// Position reports how far into the current track playback is, derived from
+// the number of Opus frames sent since the decode started and scaled by the
+// tempo of the active filters.
+func (v *VoiceInstance) Position() time.Duration {
+	return time.Duration(v.position.Load())
+}
+
+// Duration returns the length of the current track, or zero when it is not
+// known.
+func (v *VoiceInstance) Duration() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.TrackDuration
+}
+
+// Seek restarts the decode of the current track at the given offset. The
+// offset is clamped to the track length and the clamped value is returned.
+func (v *VoiceInstance) Seek(target time.Duration) (time.Duration, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.Playing {
+		return 0, fmt.Errorf("nothing is playing")
+	}
+
+	if v.CurrentTrack != nil && v.CurrentTrack.Live {
+		return 0, ErrNotSeekable
+	}
+
+	return v.requestSeekLocked(target), nil
+}
+
+// requestSeekLocked asks the frame loop to restart decoding at target. Live
+// streams ignore the offset and pick up where they are. The caller must
+// hold v.mu.
+func (v *VoiceInstance) requestSeekLocked(target time.Duration) time.Duration {
+	target = max(target, 0)
+	if v.TrackDuration > 0 && target >= v.TrackDuration {
+		target = max(v.TrackDuration-time.Second, 0)
+	}
+
+	v.seekTarget = target
+	v.seekPending = true
+
+	select {
+	case v.seekSignal <- struct{}{}:
+	default:
+	}
+
+	return target
+}
+
+// liveStation returns the current track if it is an internet radio stream.
+func (v *VoiceInstance) liveStation() *types.Track {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.CurrentTrack != nil && v.CurrentTrack.Live && v.CurrentTrack.SourceType == types.HTTP {
+		return v.CurrentTrack
+	}
+	return nil
+}
+
+// isLive reports whether the current track is an endless stream.
+func (v *VoiceInstance) isLive() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.CurrentTrack != nil && v.CurrentTrack.Live
+}
+
+// SetVolume changes the playback volume in percent. The change is ramped in
+// by the frame loop and takes effect on the next frame.
+func (v *VoiceInstance) SetVolume(percent int) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.Volume = min(max(percent, 0), maxVolume)
+	return v.Volume
+}
+
+// CurrentVolume returns the playback volume in percent.
+func (v *VoiceInstance) CurrentVolume() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.Volume
+}
+
+func (v *VoiceInstance) targetGain() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return float64(v.Volume) / 100
+}
+
+func (v *VoiceInstance) takeSeek() (time.Duration, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.seekPending {
+		return 0, false
+	}
+
+	v.seekPending = false
+
+	select {
+	case <-v.seekSignal:
+	default:
+	}
+
+	return v.seekTarget, true
 }
 
 func JoinVoiceChannel(s *discordgo.Session, guildID, channelID string) (*VoiceInstance, error) {
@@ -75,16 +294,45 @@ func JoinVoiceChannel(s *discordgo.Session, guildID, channelID string) (*VoiceIn
 	voiceInstance := &VoiceInstance{
 		GuildID:     guildID,
 		ChannelID:   channelID,
+		Session:     s,
 		Connection:  vc,
 		Playing:     false,
 		StopChannel: make(chan bool, 1),
+		seekSignal:  make(chan struct{}, 1),
+		Volume:      100,
+		gain:        1,
 		OpusEncoder: encoder,
 	}
 
+	// Leave again if nothing ends up being queued; Enqueue cancels this once
+	// playback starts.
+	voiceInstance.mu.Lock()
+	voiceInstance.startIdleTimerLocked()
+	voiceInstance.mu.Unlock()
+
 	VoiceConnection[guildID] = voiceInstance
 	return voiceInstance, nil
 }
 
+// GetVoice returns the guild's voice instance. The map is also modified by
+// idle timers and voice state updates, so it must never be read unlocked.
+func GetVoice(guildID string) (*VoiceInstance, bool) {
+	VoiceMutex.Lock()
+	defer VoiceMutex.Unlock()
+
+	voice, exists := VoiceConnection[guildID]
+	return voice, exists
+}
+
+// VoiceChannelID returns the channel the bot is in, which changes when it is
+// moved.
+func (v *VoiceInstance) VoiceChannelID() string {
+	VoiceMutex.Lock()
+	defer VoiceMutex.Unlock()
+
+	return v.ChannelID
+}
+
 func LeaveVoiceChannel(guildID string) error {
 	VoiceMutex.Lock()
 	defer VoiceMutex.Unlock()
@@ -94,6 +342,8 @@ func LeaveVoiceChannel(guildID string) error {
 		return nil
 	}
 
+	voice.stopTimers()
+	voice.ClearQueue()
 	voice.Stop()
 
 	err := voice.Connection.Disconnect()
@@ -134,8 +384,19 @@ func IsUserInSameVC(s *discordgo.Session, guildID, userID string) (bool, string)
 	return voice.ChannelID == userChannelID, userChannelID
 }
 
-func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
-	logger.Log("Starting to play: "+videoURL, types.LogOptions{
+// Active reports whether this instance is still the guild's voice connection,
+// i.e. the bot has not left or been disconnected since it was obtained.
+func (v *VoiceInstance) Active() bool {
+	VoiceMutex.Lock()
+	defer VoiceMutex.Unlock()
+
+	return VoiceConnection[v.GuildID] == v
+}
+
+// Play plays a single track from the given source, replacing whatever is
+// currently playing. It blocks until the track finishes or is stopped.
+func (v *VoiceInstance) Play(source types.SourceType, trackURL, trackID string) error {
+	logger.Log("Starting to play: "+trackURL, types.LogOptions{
 		Prefix: "Music Player",
 		Level:  types.Info,
 	})
@@ -155,7 +416,9 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 	}
 
 	v.Playing = true
-	v.CurrentTrackID = videoID
+	v.CurrentTrackID = trackID
+	v.TrackDuration = 0
+	v.position.Store(0)
 	stopChan := v.StopChannel
 	v.mu.Unlock()
 
@@ -175,39 +438,186 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	err := os.MkdirAll("./temp", 0755)
+	handoff := v.takeHandoff(trackID)
+
+	var prepared *preparedSource
+	var ok bool
+	if handoff != nil {
+		prepared, ok = handoff.source, true
+	} else {
+		prepared, ok = v.takePrefetched(trackID)
+	}
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-stopChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		var err error
+		prepared, err = prepareSource(ctx, source, trackURL, trackID, v.isLive())
+		cancel()
+		if err != nil {
+			v.mu.Lock()
+			v.Playing = false
+			v.mu.Unlock()
+			return err
+		}
+	}
+	defer prepared.release()
+
+	err := v.playAudioFile(prepared, stopChan, handoff)
 	if err != nil {
-		logger.Log("Failed to create temp directory: "+err.Error(), types.LogOptions{
+		logger.Log("Playback error: "+err.Error(), types.LogOptions{
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
-		return err
 	}
 
-	fileName := fmt.Sprintf("./temp/%s_%d.mp3", videoID, time.Now().Unix())
-	logger.Log("Downloading to: "+fileName, types.LogOptions{
-		Prefix: "Music Player",
-		Level:  types.Debug,
-	})
+	v.mu.Lock()
+	v.Playing = false
+	v.mu.Unlock()
+
+	return err
+}
 
-	var downloadCmd *exec.Cmd
-	cookiesFile := "./cookies/cookies.txt"
-	if _, err := os.Stat(cookiesFile); err == nil {
-		logger.Log("Using cookies file: "+cookiesFile, types.LogOptions{
+type preparedSource struct {
+	input    string
+	duration time.Duration
+	release  func()
+	// refresh resolves the input again for live streams, whose manifest
+	// URLs expire while they play.
+	refresh func(ctx context.Context) (string, error)
+}
+
+// prepareSource turns a track into something ffmpeg can read: a cached file,
+// a direct stream URL or a fresh download, in that order of preference.
+// Livestreams are always streamed, since their download would never finish.
+func prepareSource(ctx context.Context, sourceType types.SourceType, trackURL, trackID string, live bool) (*preparedSource, error) {
+	// Local files, direct links and uploads are already something ffmpeg can
+	// read, and radio streams never end, so none of them are downloaded or
+	// cached. Local tracks are played from the path the library indexed, and
+	// links must be plain http(s) so no other ffmpeg protocol can be reached.
+	if sourceType == types.Local {
+		entry, err := LocalLibrary.lookup(trackID)
+		if err != nil {
+			return nil, err
+		}
+		return &preparedSource{
+			input:   entry.Path,
+			release: func() {},
+		}, nil
+	}
+
+	if sourceType == types.HTTP || sourceType == types.Attachment {
+		if !isRemoteInput(trackURL) {
+			return nil, fmt.Errorf("unsupported audio URL: %q", trackURL)
+		}
+		if sourceType == types.HTTP {
+			if err := checkPublicURL(ctx, trackURL); err != nil {
+				return nil, err
+			}
+		}
+		return &preparedSource{
+			input:   trackURL,
+			release: func() {},
+		}, nil
+	}
+
+	if live {
+		streamURL, _, err := resolveStreamURL(ctx, trackURL)
+		if err != nil {
+			return nil, err
+		}
+		return &preparedSource{
+			input:   streamURL,
+			release: func() {},
+			refresh: func(ctx context.Context) (string, error) {
+				streamURL, _, err := resolveStreamURL(ctx, trackURL)
+				return streamURL, err
+			},
+		}, nil
+	}
+
+	if cachedFile, release, ok := AudioCache.Acquire(sourceType, trackID); ok {
+		logger.Log("Playing from cache: "+cachedFile, types.LogOptions{
 			Prefix: "Music Player",
 			Level:  types.Debug,
 		})
-		downloadCmd = exec.Command("yt-dlp", "--no-warnings", "--quiet", "-x", "--audio-format", "mp3",
-			"--audio-quality", "0", "--no-playlist", "--cookies", cookiesFile, "--output", fileName, videoURL)
-	} else {
-		logger.Log("No cookies file found, downloading without cookies", types.LogOptions{
+		return &preparedSource{
+			input:   cachedFile,
+			release: release,
+		}, nil
+	}
+
+	if config.Config.PlaybackMode == types.StreamPlayback {
+		streamURL, streamDuration, err := resolveStreamURL(ctx, trackURL)
+		if err == nil {
+			logger.Log("Streaming directly from source", types.LogOptions{
+				Prefix: "Music Player",
+				Level:  types.Debug,
+			})
+			if config.Config.CacheStreamed {
+				AudioCache.Fill(sourceType, trackURL, trackID)
+			}
+			return &preparedSource{
+				input:    streamURL,
+				duration: streamDuration,
+				release:  func() {},
+			}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		logger.Log("Failed to resolve stream, falling back to download: "+err.Error(), types.LogOptions{
 			Prefix: "Music Player",
-			Level:  types.Debug,
+			Level:  types.Warn,
 		})
-		downloadCmd = exec.Command("yt-dlp", "--no-warnings", "--quiet", "-x", "--audio-format", "mp3",
-			"--audio-quality", "0", "--no-playlist", "--output", fileName, videoURL)
 	}
 
+	fileName, err := downloadAudio(ctx, trackURL, trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cachedFile, release, err := AudioCache.Store(sourceType, trackID, fileName); err == nil {
+		return &preparedSource{
+			input:   cachedFile,
+			release: release,
+		}, nil
+	}
+
+	return &preparedSource{
+		input:   fileName,
+		release: func() { os.Remove(fileName) },
+	}, nil
+}
+
+// downloadAudio fetches the track with yt-dlp into ./temp as an mp3 and
+// returns the path of the downloaded file.
+func downloadAudio(ctx context.Context, videoURL, videoID string) (string, error) {
+	err := os.MkdirAll("./temp", 0755)
+	if err != nil {
+		logger.Log("Failed to create temp directory: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Error,
+		})
+		return "", err
+	}
+
+	fileName := fmt.Sprintf("./temp/%s_%d.mp3", videoID, time.Now().UnixNano())
+	logger.Log("Downloading to: "+fileName, types.LogOptions{
+		Prefix: "Music Player",
+		Level:  types.Debug,
+	})
+
+	downloadCmd := ytdlpCommand(ctx, "--quiet", "-x", "--audio-format", "mp3",
+		"--audio-quality", "0", "--no-playlist", "--output", fileName, "--", videoURL)
+
 	// Create logs for stdout and stderr to capture yt-dlp output
 	stdout, err := downloadCmd.StdoutPipe()
 	if err != nil {
@@ -215,7 +625,7 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
-		return err
+		return "", err
 	}
 	stderr, err := downloadCmd.StderrPipe()
 	if err != nil {
@@ -223,7 +633,7 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
-		return err
+		return "", err
 	}
 
 	// Start the download process
@@ -233,7 +643,7 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
-		return err
+		return "", err
 	}
 
 	// Log the stdout and stderr
@@ -270,10 +680,9 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
-		v.mu.Lock()
-		v.Playing = false
-		v.mu.Unlock()
-		return err
+		os.Remove(fileName)
+		os.Remove(fileName + ".part")
+		return "", err
 	}
 
 	logger.Log("Download complete, starting playback", types.LogOptions{
@@ -287,10 +696,7 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
-		v.mu.Lock()
-		v.Playing = false
-		v.mu.Unlock()
-		return err
+		return "", err
 	}
 
 	logger.Log(fmt.Sprintf("File size: %d bytes", fileInfo.Size()), types.LogOptions{
@@ -298,26 +704,16 @@ func (v *VoiceInstance) PlayYouTube(videoURL, videoID string) error {
 		Level:  types.Debug,
 	})
 
-	defer os.Remove(fileName)
-
-	err = v.playAudioFile(fileName, stopChan)
-	if err != nil {
-		logger.Log("Playback error: "+err.Error(), types.LogOptions{
-			Prefix: "Music Player",
-			Level:  types.Error,
-		})
-	}
-
-	v.mu.Lock()
-	v.Playing = false
-	v.mu.Unlock()
-
-	return err
+	return fileName, nil
 }
 
-func (v *VoiceInstance) playAudioFile(filename string, stopChan chan bool) error {
-	v.Connection.Speaking(false)
-	time.Sleep(50 * time.Millisecond)
+func (v *VoiceInstance) playAudioFile(prepared *preparedSource, stopChan chan bool, handoff *trackHandoff) error {
+	input, duration := prepared.input, prepared.duration
+
+	if handoff == nil {
+		v.Connection.Speaking(false)
+		time.Sleep(50 * time.Millisecond)
+	}
 
 	err := v.Connection.Speaking(true)
 	if err != nil {
@@ -325,70 +721,285 @@ func (v *VoiceInstance) playAudioFile(filename string, stopChan chan bool) error
 			Prefix: "Music Player",
 			Level:  types.Error,
 		})
+		if handoff != nil {
+			handoff.decoder.close()
+		}
 		return err
 	}
-	defer v.Connection.Speaking(false)
+	defer func() {
+		// Keep speaking when the next track is already decoding so the
+		// transition stays seamless.
+		if v.currentHandoff() == nil {
+			v.Connection.Speaking(false)
+		}
+	}()
 
-	ffmpeg := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "quiet", "-i", filename, "-f", "s16le", "-ar", "48000", "-ac", "2", "pipe:1")
-	ffmpegout, err := ffmpeg.StdoutPipe()
-	if err != nil {
-		logger.Log("FFmpeg pipe error: "+err.Error(), types.LogOptions{
-			Prefix: "Music Player",
-			Level:  types.Error,
-		})
-		return err
+	live := v.isLive()
+	liveRestarts := 0
+	restartedAt := time.Now()
+
+	if duration == 0 && !live {
+		duration, err = probeDuration(input)
+		if err != nil {
+			logger.Log("FFprobe error: "+err.Error(), types.LogOptions{
+				Prefix: "Music Player",
+				Level:  types.Warn,
+			})
+		}
 	}
 
-	ffmpeg.Stderr = nil
-	err = ffmpeg.Start()
-	if err != nil {
-		logger.Log("FFmpeg start error: "+err.Error(), types.LogOptions{
+	v.mu.Lock()
+	v.TrackDuration = duration
+	v.seekPending = false
+	v.mu.Unlock()
+	v.position.Store(0)
+
+	var dec *decoder
+	offset := time.Duration(0)
+	if handoff != nil {
+		dec = handoff.decoder
+		offset = handoff.position
+		v.position.Store(int64(offset))
+	}
+
+	for {
+		if dec == nil {
+			dec, err = v.startDecoder(input, offset)
+			if err != nil {
+				return err
+			}
+		}
+
+		result := v.streamFrames(dec, offset == 0, stopChan)
+		dec.close()
+		dec = nil
+
+		if result.reconnect {
+			if err := v.reconnect(stopChan); err != nil {
+				logger.Log(err.Error(), types.LogOptions{
+					Prefix: "Voice Reconnect",
+					Level:  types.Error,
+				})
+				return err
+			}
+			offset = v.Position()
+			continue
+		}
+
+		// A livestream only ends when it is stopped, so running out of input
+		// is a dropped connection or an HLS hiccup: start decoding again.
+		if result.endOfInput && live {
+			if time.Since(restartedAt) > liveRestartWindow {
+				liveRestarts = 0
+			}
+			if liveRestarts >= maxLiveRestarts {
+				return fmt.Errorf("live stream could not be reached after %d attempts", maxLiveRestarts)
+			}
+			liveRestarts++
+			restartedAt = time.Now()
+
+			logger.Log(fmt.Sprintf("Live stream input ended, restarting decoder (attempt %d/%d)", liveRestarts, maxLiveRestarts), types.LogOptions{
+				Prefix: "Music Player",
+				Level:  types.Warn,
+			})
+			if !sleepOrStop(liveRestartDelay, stopChan) {
+				return nil
+			}
+
+			if prepared.refresh != nil {
+				ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+				streamURL, err := prepared.refresh(ctx)
+				cancel()
+				if err != nil {
+					logger.Log("Failed to resolve live stream again, reusing the previous URL: "+err.Error(), types.LogOptions{
+						Prefix: "Music Player",
+						Level:  types.Warn,
+					})
+				} else {
+					input = streamURL
+				}
+			}
+			continue
+		}
+
+		if !result.seeking {
+			return result.err
+		}
+
+		logger.Log("Seeking to "+FormatDuration(result.seekTo), types.LogOptions{
 			Prefix: "Music Player",
-			Level:  types.Error,
+			Level:  types.Debug,
 		})
-		return err
+		offset = result.seekTo
+		v.position.Store(int64(offset))
 	}
+}
 
-	ffmpegProcess := ffmpeg.Process
-	defer func() {
-		ffmpegProcess.Kill()
-		ffmpeg.Wait()
-	}()
+type frameLoopResult struct {
+	err        error
+	seeking    bool
+	seekTo     time.Duration
+	reconnect  bool
+	endOfInput bool
+}
+
+// streamFrames sends the frames produced by the decoder until the input
+// ends, the track is stopped or a seek is requested. Near the end of the
+// track it starts the next one and, depending on the guild settings, fades
+// it in or trims trailing silence so the player can hand over without a gap.
+func (v *VoiceInstance) streamFrames(dec *decoder, fromStart bool, stopChan chan bool) frameLoopResult {
+	v.mu.Lock()
+	trackDuration := v.TrackDuration
+	v.mu.Unlock()
+
+	transitionLead, crossfade, gapless := v.transitionSettings()
+	frameAdvance := int64(dec.frameAdvance())
 
 	buf := make([]int16, frameSize*channels)
+	nextBuf := make([]int16, frameSize*channels)
 
-	playbackDone := make(chan error, 1)
+	sendTimer := time.NewTimer(voiceSendTimeout)
+	defer sendTimer.Stop()
+
+	playbackDone := make(chan frameLoopResult, 1)
 	go func() {
+		var transition *trackHandoff
+		transitionTried := false
+		fadeFrames, fadeDone := 0, 0
+		trimLeading := gapless && fromStart
+		silentRun := time.Duration(0)
+
 		for {
-			err = binary.Read(ffmpegout, binary.LittleEndian, &buf)
-			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				playbackDone <- nil
+			if !v.waitIfPaused(stopChan) {
+				playbackDone <- frameLoopResult{}
+				return
+			}
+
+			if target, ok := v.takeSeek(); ok {
+				if transition != nil {
+					v.discardHandoff(transition)
+				}
+				playbackDone <- frameLoopResult{seeking: true, seekTo: target}
+				return
+			}
+
+			err := dec.readFrame(buf)
+			if err == io.EOF {
+				playbackDone <- frameLoopResult{endOfInput: true}
 				return
 			}
 			if err != nil {
-				playbackDone <- err
+				playbackDone <- frameLoopResult{err: err}
 				return
 			}
 
+			if trimLeading {
+				if isSilent(buf) && v.Position() < maxLeadingSilence {
+					v.position.Add(frameAdvance)
+					continue
+				}
+				trimLeading = false
+			}
+
+			remaining := trackDuration - v.Position()
+
+			if !transitionTried && transitionLead > 0 && trackDuration > 0 && remaining <= transitionLead {
+				transitionTried = true
+				transition = v.beginTransition()
+				if transition != nil && crossfade > 0 {
+					fadeFrames = max(int(min(remaining, crossfade)/frameDuration), 1)
+				}
+			}
+
+			if transition != nil && fadeFrames > 0 {
+				if v.currentHandoff() != transition {
+					transition = nil
+				} else {
+					if err := transition.decoder.readFrame(nextBuf); err != nil {
+						clear(nextBuf)
+					} else {
+						transition.position += transition.decoder.frameAdvance()
+					}
+
+					from := float64(fadeDone) / float64(fadeFrames)
+					to := float64(fadeDone+1) / float64(fadeFrames)
+					crossfadeFrames(buf, nextBuf, from, to)
+					fadeDone++
+				}
+			}
+
+			if gapless && trackDuration > 0 && remaining <= trailingSilenceWindow {
+				if isSilent(buf) {
+					silentRun += frameDuration
+				} else {
+					silentRun = 0
+				}
+
+				if silentRun >= trailingSilenceRun {
+					if transition == nil && !transitionTried {
+						transitionTried = true
+						v.beginTransition()
+					}
+					playbackDone <- frameLoopResult{}
+					return
+				}
+			}
+
+			target := v.targetGain()
+			next := rampGain(v.gain, target)
+			applyGain(buf, v.gain, next)
+			v.gain = next
+
 			opus, err := v.OpusEncoder.Encode(buf, frameSize, maxBytes)
 			if err != nil {
-				playbackDone <- err
+				playbackDone <- frameLoopResult{err: err}
+				return
+			}
+
+			if !v.connectionAlive() {
+				if transition != nil {
+					v.discardHandoff(transition)
+				}
+				playbackDone <- frameLoopResult{reconnect: true}
 				return
 			}
 
+			sendTimer.Reset(voiceSendTimeout)
 			select {
 			case v.Connection.OpusSend <- opus:
+				v.position.Add(frameAdvance)
+				// Tracks of unknown length, live ones included, never
+				// prefetch: the next stream URL could expire before it plays.
+				if trackDuration > 0 && trackDuration-v.Position() <= prefetchLead {
+					v.maybePrefetch()
+				}
+			case <-sendTimer.C:
+				if transition != nil {
+					v.discardHandoff(transition)
+				}
+				playbackDone <- frameLoopResult{reconnect: true}
+				return
 			case <-stopChan:
-				playbackDone <- nil
+				playbackDone <- frameLoopResult{}
+				return
+			}
+
+			if fadeFrames > 0 && fadeDone >= fadeFrames {
+				// The current track has faded out completely; the next one
+				// carries on from the handoff.
+				playbackDone <- frameLoopResult{}
 				return
 			}
 		}
 	}()
 
 	select {
-	case err := <-playbackDone:
-		if err != nil {
-			logger.Log("Playback error: "+err.Error(), types.LogOptions{
+	case result := <-playbackDone:
+		if result.seeking || result.reconnect {
+			return result
+		}
+		if result.err != nil {
+			logger.Log("Playback error: "+result.err.Error(), types.LogOptions{
 				Prefix: "Music Player",
 				Level:  types.Error,
 			})
@@ -398,12 +1009,16 @@ func (v *VoiceInstance) playAudioFile(filename string, stopChan chan bool) error
 				Level:  types.Success,
 			})
 		}
-		return err
+		return result
 	case <-stopChan:
 		logger.Log("Playback stopped by request", types.LogOptions{
 			Prefix: "Music Player",
 			Level:  types.Info,
 		})
-		return nil
+		// Unblock the frame loop and wait for it so it cannot keep reading
+		// from a decoder handed over to the next track.
+		dec.close()
+		<-playbackDone
+		return frameLoopResult{}
 	}
 }
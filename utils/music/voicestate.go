@@ -0,0 +1,118 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// HandleBotVoiceState reconciles the guild's voice instance with a voice
+// state update about the bot itself: it follows moves to another channel,
+// tears down after a forced disconnect and pauses while server-muted.
+func HandleBotVoiceState(s *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
+	VoiceMutex.Lock()
+	voice, exists := VoiceConnection[vs.GuildID]
+	if !exists {
+		VoiceMutex.Unlock()
+		return
+	}
+
+	if vs.ChannelID == "" {
+		if voice.isReconnecting() {
+			VoiceMutex.Unlock()
+			logger.Log("Ignoring voice disconnect while reconnecting in guild "+vs.GuildID, types.LogOptions{
+				Prefix: "Voice Reconnect",
+				Level:  types.Debug,
+			})
+			return
+		}
+
+		delete(VoiceConnection, vs.GuildID)
+		VoiceMutex.Unlock()
+
+		voice.handleForcedDisconnect()
+		return
+	}
+
+	moved := voice.ChannelID != vs.ChannelID
+	if moved {
+		logger.Log(fmt.Sprintf("Moved to voice channel %s in guild %s", vs.ChannelID, vs.GuildID), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Info,
+		})
+		voice.ChannelID = vs.ChannelID
+	}
+	VoiceMutex.Unlock()
+
+	if moved {
+		voice.CheckListeners(s)
+	}
+
+	voice.handleServerMute(vs.Mute)
+}
+
+func (v *VoiceInstance) handleForcedDisconnect() {
+	logger.Log("Disconnected from voice by someone else in guild "+v.GuildID, types.LogOptions{
+		Prefix: "Music Player",
+		Level:  types.Warn,
+	})
+
+	v.stopTimers()
+	v.ClearQueue()
+	v.Stop()
+
+	// The gateway already dropped us; this only cleans up the local
+	// connection state, so errors are expected and ignored.
+	v.Connection.Disconnect()
+
+	v.notify("⚠️ I was disconnected from the voice channel, so I stopped playback and cleared the queue.")
+}
+
+func (v *VoiceInstance) handleServerMute(muted bool) {
+	v.mu.Lock()
+	wasMuted := v.ServerMuted
+	v.ServerMuted = muted
+	pausedByMute := v.pausedByMute
+	v.mu.Unlock()
+
+	if muted && !wasMuted {
+		if v.Pause() {
+			v.mu.Lock()
+			v.pausedByMute = true
+			v.mu.Unlock()
+			v.notify("🔇 I was server-muted, so playback is paused until I'm unmuted.")
+		}
+		return
+	}
+
+	if !muted && wasMuted && pausedByMute {
+		v.mu.Lock()
+		v.pausedByMute = false
+		v.mu.Unlock()
+
+		if v.Resume() {
+			v.notify("🔊 I was unmuted, resuming playback.")
+		}
+	}
+}
+
+// notify posts a message in the text channel the bot was last used from.
+func (v *VoiceInstance) notify(message string) {
+	v.mu.Lock()
+	session := v.Session
+	channelID := v.TextChannelID
+	v.mu.Unlock()
+
+	if session == nil || channelID == "" {
+		return
+	}
+
+	if _, err := session.ChannelMessageSend(channelID, message); err != nil {
+		logger.Log("Failed to send notice: "+err.Error(), types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Error,
+		})
+	}
+}
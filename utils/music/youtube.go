@@ -0,0 +1,255 @@
+package music
+
+import (
+	"ai/config"
+	"ai/types"
+	"ai/utils/logger"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// youtubeAPIError is a non-200 response from the YouTube Data API.
+type youtubeAPIError struct {
+	Status  string
+	Reason  string
+	Message string
+}
+
+func (e *youtubeAPIError) Error() string {
+	if e.Message == "" {
+		return "youtube API returned " + e.Status
+	}
+	return fmt.Sprintf("youtube API returned %s: %s", e.Status, e.Message)
+}
+
+// youtubeKeyErrors are the Data API reasons for an unusable key.
+var youtubeKeyErrors = []string{"keyInvalid", "keyExpired", "accessNotConfigured", "ipRefererBlocked"}
+
+// youtubeKeyRetry is how long lookups avoid the API after it rejects the key.
+const youtubeKeyRetry = time.Hour
+
+// isQuotaError reports whether the API key has used up its daily quota.
+func isQuotaError(err error) bool {
+	var apiErr *youtubeAPIError
+	return errors.As(err, &apiErr) && (apiErr.Reason == "quotaExceeded" || apiErr.Reason == "dailyLimitExceeded")
+}
+
+func isKeyError(err error) bool {
+	var apiErr *youtubeAPIError
+	return errors.As(err, &apiErr) && slices.Contains(youtubeKeyErrors, apiErr.Reason)
+}
+
+// shouldUseYtdlp reports whether a failed API request should go to yt-dlp.
+func shouldUseYtdlp(err error) bool {
+	return isQuotaError(err) || isKeyError(err)
+}
+
+// youtubeAPIState records until when lookups skip the API.
+var youtubeAPIState struct {
+	mu            sync.Mutex
+	disabledUntil time.Time
+}
+
+// youtubeAPIAvailable reports whether a key is set and not currently disabled.
+func youtubeAPIAvailable() bool {
+	if config.Config.YoutubeAPIKey == "" {
+		return false
+	}
+
+	youtubeAPIState.mu.Lock()
+	defer youtubeAPIState.mu.Unlock()
+
+	return time.Now().After(youtubeAPIState.disabledUntil)
+}
+
+// quotaResetTime returns the next midnight Pacific time.
+func quotaResetTime() time.Time {
+	location, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return time.Now().Add(time.Hour)
+	}
+
+	now := time.Now().In(location)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, location)
+}
+
+// disableYouTubeAPI sends lookups to yt-dlp until the given time.
+func disableYouTubeAPI(until time.Time, reason string) {
+	youtubeAPIState.mu.Lock()
+	alreadyDisabled := time.Now().Before(youtubeAPIState.disabledUntil)
+	youtubeAPIState.disabledUntil = until
+	youtubeAPIState.mu.Unlock()
+
+	if !alreadyDisabled {
+		logger.Log(reason+", using yt-dlp until "+until.Local().Format(time.Kitchen), types.LogOptions{
+			Prefix: "Search",
+			Level:  types.Warn,
+		})
+	}
+}
+
+// youtubeGet performs a Data API request and decodes the response into out.
+func youtubeGet(apiURL string, out interface{}) error {
+	return youtubeGetContext(context.Background(), apiURL, out)
+}
+
+func youtubeGetContext(ctx context.Context, apiURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var response struct {
+			Error struct {
+				Message string `json:"message"`
+				Errors  []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		apiErr := &youtubeAPIError{Status: resp.Status}
+		if json.Unmarshal(body, &response) == nil {
+			apiErr.Message = response.Error.Message
+			if len(response.Error.Errors) > 0 {
+				apiErr.Reason = response.Error.Errors[0].Reason
+			}
+		}
+
+		if resp.StatusCode == http.StatusForbidden && isQuotaError(apiErr) {
+			disableYouTubeAPI(quotaResetTime(), "YouTube API quota exceeded")
+		} else if isKeyError(apiErr) {
+			disableYouTubeAPI(time.Now().Add(youtubeKeyRetry), "YouTube API rejected the key ("+apiErr.Reason+")")
+		}
+		return apiErr
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// ytdlpVideo is a YouTube video as yt-dlp reports it.
+type ytdlpVideo struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Channel    string  `json:"channel"`
+	Uploader   string  `json:"uploader"`
+	Duration   float64 `json:"duration"`
+	LiveStatus string  `json:"live_status"`
+	Thumbnail  string  `json:"thumbnail"`
+	Thumbnails []struct {
+		URL string `json:"url"`
+	} `json:"thumbnails"`
+}
+
+func (video ytdlpVideo) result() types.MusicSearchResult {
+	artist := video.Channel
+	if artist == "" {
+		artist = video.Uploader
+	}
+
+	thumbnail := video.Thumbnail
+	if thumbnail == "" && len(video.Thumbnails) > 0 {
+		thumbnail = video.Thumbnails[len(video.Thumbnails)-1].URL
+	}
+
+	result := types.MusicSearchResult{
+		Title:      video.Title,
+		Artist:     artist,
+		URL:        fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.ID),
+		ID:         video.ID,
+		Duration:   "00:00",
+		Thumbnail:  thumbnail,
+		SourceType: types.YouTube,
+		Live:       video.LiveStatus == "is_live",
+	}
+
+	if result.Live {
+		result.Duration = ""
+	} else if video.Duration > 0 {
+		result.Duration = FormatDuration(time.Duration(video.Duration * float64(time.Second)))
+	}
+
+	return result
+}
+
+// searchYouTubeYtdlp searches YouTube without an API key.
+func searchYouTubeYtdlp(ctx context.Context, query string, limit int) ([]types.MusicSearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	output, err := ytdlpCommand(ctx, "--flat-playlist", "--dump-json", "--",
+		fmt.Sprintf("ytsearch%d:%s", limit, query)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp YouTube search failed: %w", err)
+	}
+
+	results := []types.MusicSearchResult{}
+
+	// Each search entry is printed as its own JSON object on one line.
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var video ytdlpVideo
+		if err := json.Unmarshal(scanner.Bytes(), &video); err != nil || video.ID == "" {
+			continue
+		}
+		results = append(results, video.result())
+	}
+
+	return results, scanner.Err()
+}
+
+// getYouTubeInfoYtdlp looks up a video, returning a BroadcastError for unplayable streams.
+func getYouTubeInfoYtdlp(videoID string) (types.MusicSearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	output, err := ytdlpCommand(ctx, "--no-playlist", "--dump-json", "--",
+		"https://www.youtube.com/watch?v="+videoID).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "will begin") || strings.Contains(stderr, "Premieres in") {
+				return types.MusicSearchResult{}, &BroadcastError{Upcoming: true}
+			}
+		}
+		return types.MusicSearchResult{}, fmt.Errorf("yt-dlp could not load video: %w", err)
+	}
+
+	var video ytdlpVideo
+	if err := json.Unmarshal(output, &video); err != nil {
+		return types.MusicSearchResult{}, err
+	}
+
+	switch video.LiveStatus {
+	case "is_upcoming":
+		return types.MusicSearchResult{}, &BroadcastError{Upcoming: true}
+	case "post_live":
+		return types.MusicSearchResult{}, &BroadcastError{}
+	}
+
+	return video.result(), nil
+}
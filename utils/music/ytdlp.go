@@ -0,0 +1,69 @@
+package music
+
+import (
+	"ai/types"
+	"ai/utils/logger"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cookiesFile = "./cookies/cookies.txt"
+
+// ytdlpCommand builds a yt-dlp invocation, adding the cookies file when one
+// is present so age-restricted and members-only content keeps working.
+// Callers put "--" before URLs and queries so they are never read as options.
+func ytdlpCommand(ctx context.Context, args ...string) *exec.Cmd {
+	baseArgs := []string{"--no-warnings"}
+
+	if _, err := os.Stat(cookiesFile); err == nil {
+		logger.Log("Using cookies file: "+cookiesFile, types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Debug,
+		})
+		baseArgs = append(baseArgs, "--cookies", cookiesFile)
+	} else {
+		logger.Log("No cookies file found, running yt-dlp without cookies", types.LogOptions{
+			Prefix: "Music Player",
+			Level:  types.Debug,
+		})
+	}
+
+	return exec.CommandContext(ctx, "yt-dlp", append(baseArgs, args...)...)
+}
+
+// resolveStreamURL asks yt-dlp for the direct URL of the best audio-only
+// format so ffmpeg can decode it while it downloads, skipping the mp3
+// transcode of the download path.
+func resolveStreamURL(ctx context.Context, videoURL string) (string, time.Duration, error) {
+	output, err := ytdlpCommand(ctx, "--no-playlist", "-f", "bestaudio/best",
+		"--print", "duration", "--print", "urls", "--", videoURL).Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("yt-dlp could not resolve stream: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) < 2 {
+		return "", 0, fmt.Errorf("unexpected yt-dlp output: %q", string(output))
+	}
+
+	var duration time.Duration
+	if seconds, err := strconv.ParseFloat(strings.TrimSpace(lines[0]), 64); err == nil {
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	streamURL := strings.TrimSpace(lines[1])
+	if !isRemoteInput(streamURL) {
+		return "", 0, fmt.Errorf("unexpected stream URL: %q", streamURL)
+	}
+
+	return streamURL, duration, nil
+}
+
+func isRemoteInput(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}